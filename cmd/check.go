@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkCmd compares what a run would write with the output directory, without writing anything.
+var checkCmd = &cobra.Command{
+	Use:   "check [paths...]",
+	Short: "Fail if extracted files are out of date",
+	Long: `Extracts the code blocks in memory with the same options as a normal run and
+compares them with the files in the output directory. Every missing or changed
+file, and every previously generated file that no block produces any more, is
+printed as a unified diff, and the command exits non-zero if there are any, so
+that a CI job can enforce that documentation and code stay in sync.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		return runCheck(args, cmd.OutOrStdout(), os.Stderr)
+	},
+}
+
+// runCheck plans a run over args with the configured flags and writes a diff to stdout for every
+// file the run would change. It fails if there are any; otherwise it reports on stderr.
+func runCheck(args []string, stdout, stderr io.Writer) error {
+	extractions, err := extractAll(args)
+	if err != nil {
+		return err
+	}
+	outputDirectory, err := outputDirectory()
+	if err != nil {
+		return err
+	}
+	onConflict := viper.GetString("on-conflict")
+	if err := validateConflictPolicy(onConflict); err != nil {
+		return err
+	}
+	separator, err := concatSeparator()
+	if err != nil {
+		return err
+	}
+	plan := newPlan(extractions, outputDirectory, planOptions{onConflict: onConflict, separator: separator})
+	if err := plan.conflictErrors(); err != nil {
+		return err
+	}
+	previous, err := readManifest(outputDirectory)
+	if err != nil {
+		return err
+	}
+	plan.addStale(previous, outputDirectory, false)
+
+	outdated, err := plan.check(stdout)
+	if err != nil {
+		return err
+	}
+	counts := plan.counts()
+	checked := counts[actionCreate] + counts[actionOverwrite] + len(plan.stale)
+	if outdated > 0 {
+		return fmt.Errorf("%d of %d files out of date", outdated, checked)
+	}
+	fmt.Fprintf(stderr, "%d files up to date\n", checked)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+// check writes a unified diff to w for every file of the plan whose content on disk differs from
+// its block, treating a missing file as empty, and for every stale file, as a deletion. It returns
+// the number of such files.
+func (p plan) check(w io.Writer) (int, error) {
+	outdated := 0
+	for _, entry := range p.entries {
+		if entry.action != actionCreate && entry.action != actionOverwrite {
+			continue
+		}
+		fromName := "a/" + entry.sourceCode.Filename
+		existing, err := os.ReadFile(entry.destination)
+		if os.IsNotExist(err) {
+			fromName = "/dev/null"
+		} else if err != nil {
+			return outdated, err
+		}
+		if string(existing) == entry.sourceCode.Content {
+			continue
+		}
+		outdated++
+		if err := unifiedDiff(w, fromName, "b/"+entry.sourceCode.Filename, string(existing), entry.sourceCode.Content); err != nil {
+			return outdated, err
+		}
+	}
+	for _, stale := range p.stale {
+		existing, err := os.ReadFile(stale.destination)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return outdated, err
+		}
+		outdated++
+		if err := unifiedDiff(w, "a/"+stale.Path, "/dev/null", string(existing), ""); err != nil {
+			return outdated, err
+		}
+	}
+	return outdated, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanCheck(t *testing.T) {
+	outputDirectory := t.TempDir()
+	writeTree(t, outputDirectory, map[string]string{
+		"current.go": "package current\n",
+		"stale.go":   "package old\n",
+	})
+
+	markdown := "```go filename=\"current.go\"\npackage current\n```\n\n" +
+		"```go filename=\"stale.go\"\npackage stale\n```\n\n" +
+		"```go filename=\"missing.go\"\npackage missing\n```\n"
+	plan := newPlan(extractMarkdown(t, markdown), outputDirectory, planOptions{onConflict: conflictError})
+
+	var diff bytes.Buffer
+	outdated, err := plan.check(&diff)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if outdated != 2 {
+		t.Errorf("Expected 2 outdated files, got %d", outdated)
+	}
+	expected := "--- a/stale.go\n+++ b/stale.go\n@@ -1 +1 @@\n-package old\n+package stale\n" +
+		"--- /dev/null\n+++ b/missing.go\n@@ -0,0 +1 @@\n+package missing\n"
+	if diff.String() != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, diff.String())
+	}
+
+	// Checking never writes anything
+	if fileExists(filepath.Join(outputDirectory, "missing.go")) {
+		t.Error("Expected check to leave the output directory alone")
+	}
+	if content, _ := os.ReadFile(filepath.Join(outputDirectory, "stale.go")); !strings.Contains(string(content), "old") {
+		t.Error("Expected check not to update stale.go")
+	}
+}
+
+func TestPlanCheckStale(t *testing.T) {
+	outputDirectory := t.TempDir()
+	generate(t, "```go filename=\"kept.go\"\npackage kept\n```\n\n```go filename=\"removed.go\"\npackage removed\n```\n", outputDirectory, false)
+
+	plan := newPlan(extractMarkdown(t, "```go filename=\"kept.go\"\npackage kept\n```\n"), outputDirectory, planOptions{onConflict: conflictError})
+	previous, err := readManifest(outputDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.addStale(previous, outputDirectory, false)
+
+	var diff bytes.Buffer
+	outdated, err := plan.check(&diff)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "--- a/removed.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package removed\n"
+	if outdated != 1 || diff.String() != expected {
+		t.Errorf("Expected the stale file as a deletion, got %d:\n%s", outdated, diff.String())
+	}
+}
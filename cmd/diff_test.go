@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	numbered := func(from, to int) string {
+		var sb strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&sb, "line %d\n", i)
+		}
+		return sb.String()
+	}
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{"equal", "a\n", "a\n", ""},
+		{
+			"new file",
+			"",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"change with context",
+			"package a\n\nfunc A() {}\n",
+			"package a\n\nfunc A() { panic(1) }\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n package a\n \n-func A() {}\n+func A() { panic(1) }\n",
+		},
+		{
+			"missing newline",
+			"a\nb",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			"separate hunks",
+			numbered(1, 20),
+			strings.Replace(strings.Replace(numbered(1, 20), "line 2\n", "", 1), "line 18\n", "line 18b\n", 1),
+			"--- old\n+++ new\n" +
+				"@@ -1,5 +1,4 @@\n line 1\n-line 2\n line 3\n line 4\n line 5\n" +
+				"@@ -15,6 +14,6 @@\n line 15\n line 16\n line 17\n-line 18\n+line 18b\n line 19\n line 20\n",
+		},
+		{
+			"nearby changes share a hunk",
+			numbered(1, 10),
+			strings.Replace(strings.Replace(numbered(1, 10), "line 2\n", "two\n", 1), "line 8\n", "eight\n", 1),
+			"--- old\n+++ new\n@@ -1,10 +1,10 @@\n line 1\n-line 2\n+two\n line 3\n line 4\n line 5\n line 6\n line 7\n-line 8\n+eight\n line 9\n line 10\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := unifiedDiff(&buf, "old", "new", tt.from, tt.to); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected diff:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	a := splitLines("a\nb\nc\na\nb\nb\na\n")
+	b := splitLines("c\nb\na\nb\na\nc\n")
+	changes := 0
+	for _, op := range diffLines(a, b) {
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	// The classic example from Myers' paper has an edit distance of 5
+	if changes != 5 {
+		t.Errorf("Expected 5 changes, got %d", changes)
+	}
+}
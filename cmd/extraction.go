@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spandigitial/codeblocks/extract"
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/spf13/viper"
+)
+
+// extraction is a code block together with the source file it is extracted to.
+type extraction struct {
+	block      model.FencedCodeBlock
+	extension  string
+	sourceCode model.SourceCode // Filename is slash-separated and relative to the output directory
+	tangled    bool             // the content was assembled from chunks, see extract.Tangle
+}
+
+// extractAll resolves the input paths and extracts and names every block according to the
+// configured flags, without touching the filesystem beyond reading the inputs.
+func extractAll(args []string) ([]extraction, error) {
+	paths := args
+	if input := viper.GetString("input"); input != "" {
+		paths = append([]string{input}, paths...)
+	}
+	documents, err := resolveInputs(paths, inputOptions{
+		include:   viper.GetStringSlice("include"),
+		exclude:   viper.GetStringSlice("exclude"),
+		gitignore: viper.GetBool("gitignore"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filenamePrefix := viper.GetString("filename-prefix")
+	if filenamePrefix == "" {
+		filenamePrefix = "sourcecode"
+	}
+
+	var nameTemplate *template.Template
+	if text := viper.GetString("name-template"); text != "" {
+		if nameTemplate, err = parseNameTemplate(text); err != nil {
+			return nil, err
+		}
+	}
+
+	// Filters apply after tangling, so that chunks outside the selection still expand the references of selected blocks
+	var filters []extract.Filter
+	if include, exclude := viper.GetStringSlice("lang"), viper.GetStringSlice("exclude-lang"); len(include) > 0 || len(exclude) > 0 {
+		filters = append(filters, languageFilter(include, exclude))
+	}
+	if selectors := viper.GetStringSlice("section"); len(selectors) > 0 {
+		filter, err := sectionFilter(selectors)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	skipped := make(map[extract.SkipReason]int)
+	options := []extract.Option{
+		extract.WithUntagged(viper.GetBool("include-untagged")),
+		extract.WithEmpty(viper.GetBool("include-empty")),
+		extract.WithIndented(viper.GetBool("include-indented")),
+		extract.WithSkipped(func(_ model.FencedCodeBlock, reason extract.SkipReason) {
+			skipped[reason]++
+		}),
+		extract.WithFallbackLanguage(fallbackLanguage(viper.GetString("untagged-language"), viper.GetString("indented-language"), viper.GetBool("detect-language"))),
+	}
+	extractor := extract.New(options...)
+
+	var extractions []extraction
+	for _, document := range documents {
+		var codeBlocks []model.FencedCodeBlock
+		if document.path == "" {
+			codeBlocks, err = extractor.ExtractReader(os.Stdin)
+		} else {
+			codeBlocks, err = extractor.ExtractFile(document.path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		original := make(map[int]string, len(codeBlocks))
+		for _, codeBlock := range codeBlocks {
+			original[codeBlock.Position.StartOffset] = codeBlock.Content
+		}
+		if codeBlocks, err = extract.Tangle(codeBlocks); err != nil {
+			return nil, err
+		}
+		codeBlocks = filterBlocks(codeBlocks, filters)
+
+		namer := newBlockNamer(nameTemplate, filenamePrefix, viper.GetString("extension"), document.path, codeBlocks)
+		for _, codeBlock := range codeBlocks {
+			filename, err := namer.next(codeBlock)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := codeBlock.Attributes.Mode(); err != nil {
+				return nil, fmt.Errorf("%s: invalid mode attribute: %w", codeBlock.Position, err)
+			}
+			extractions = append(extractions, extraction{
+				block:     codeBlock,
+				extension: namer.extensionFor(codeBlock),
+				tangled:   original[codeBlock.Position.StartOffset] != codeBlock.Content,
+				sourceCode: codeBlock.ToSourceCode(func(block model.FencedCodeBlock) string {
+					return path.Join(document.outputDir, filename)
+				}),
+			})
+		}
+	}
+
+	if skipped[extract.SkipUntagged] > 0 || skipped[extract.SkipEmpty] > 0 {
+		fmt.Fprintf(os.Stderr, "Ignored %d untagged and %d empty blocks (see --include-untagged and --include-empty)\n",
+			skipped[extract.SkipUntagged], skipped[extract.SkipEmpty])
+	}
+	return extractions, nil
+}
+
+// detectionThreshold is the confidence model.DetectLanguage must reach for --detect-language to use its guess.
+const detectionThreshold = 0.5
+
+// fallbackLanguage returns the language of blocks without a known one. A block without a language
+// gets the configured language for untagged fenced blocks or indented blocks, or else the interpreter
+// named by a shebang line. With detect, a guess of model.DetectLanguage is used instead of the shebang,
+// and also replaces a language tag that is not known; otherwise unknown tags are kept.
+func fallbackLanguage(untagged, indented string, detect bool) func(model.FencedCodeBlock) string {
+	return func(block model.FencedCodeBlock) string {
+		if block.Language != "" {
+			if detected := detectLanguage(block.Content, detect); detected != "" {
+				return detected
+			}
+			return block.Language
+		}
+		language := untagged
+		if block.Indented {
+			language = indented
+		}
+		if language == "" {
+			language = detectLanguage(block.Content, detect)
+		}
+		if language == "" {
+			language = model.ShebangLanguage(block.Content)
+		}
+		return language
+	}
+}
+
+// detectLanguage returns the language model.DetectLanguage guesses for content if detect is set and
+// the guess reaches detectionThreshold.
+func detectLanguage(content string, detect bool) string {
+	if !detect {
+		return ""
+	}
+	language, confidence := model.DetectLanguage(content)
+	if confidence < detectionThreshold {
+		return ""
+	}
+	return language
+}
+
+// outputDirectory returns the configured output directory, defaulting to the working directory.
+func outputDirectory() (string, error) {
+	if directory := viper.GetString("output-directory"); directory != "" {
+		return directory, nil
+	}
+	return os.Getwd()
+}
+
+// concatSeparator returns the configured --concat-separator with Go escape sequences such as \n interpreted.
+func concatSeparator() (string, error) {
+	text := viper.GetString("concat-separator")
+	separator, err := strconv.Unquote(`"` + strings.ReplaceAll(text, `"`, `\"`) + `"`)
+	if err != nil {
+		return "", fmt.Errorf("invalid --concat-separator %q: %w", text, err)
+	}
+	return separator, nil
+}
+
+// newWriter returns the writer for the output directory configured by --dir-mode, --file-mode and --verbose.
+func newWriter(outputDirectory string) (model.Writer, error) {
+	dirMode, err := configMode("dir-mode")
+	if err != nil {
+		return model.Writer{}, fmt.Errorf("invalid --dir-mode: %w", err)
+	}
+	fileMode, err := configMode("file-mode")
+	if err != nil {
+		return model.Writer{}, fmt.Errorf("invalid --file-mode: %w", err)
+	}
+	return model.Writer{
+		Root:     outputDirectory,
+		DirMode:  dirMode,
+		FileMode: fileMode,
+		Verbose:  viper.GetBool("verbose"),
+	}, nil
+}
+
+// configMode returns the permission configured under key. Flags and environment variables give
+// octal text, but YAML reads an unquoted 0644 in the config file as the integer 420.
+func configMode(key string) (os.FileMode, error) {
+	if value, ok := viper.Get(key).(int); ok {
+		return model.ParseMode(fmt.Sprintf("%o", value))
+	}
+	return model.ParseMode(viper.GetString(key))
+}
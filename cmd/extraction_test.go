@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// setConfig overrides viper settings for the duration of the test.
+func setConfig(t *testing.T, settings map[string]any) {
+	t.Helper()
+	for key, value := range settings {
+		previous := viper.Get(key)
+		viper.Set(key, value)
+		t.Cleanup(func() { viper.Set(key, previous) })
+	}
+}
+
+// setInput writes markdown to a temporary file and makes it the input of the current settings.
+func setInput(t *testing.T, markdown string) {
+	t.Helper()
+	input := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(input, []byte(markdown), 0644); err != nil {
+		t.Fatalf("Failed to write markdown: %v", err)
+	}
+	setConfig(t, map[string]any{"input": input})
+}
+
+// extractMarkdown writes markdown to a temporary file and extracts it with the current settings.
+func extractMarkdown(t *testing.T, markdown string) []extraction {
+	t.Helper()
+	setInput(t, markdown)
+
+	extractions, err := extractAll(nil)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	return extractions
+}
+
+func TestModeAttribute(t *testing.T) {
+	extractions := extractMarkdown(t, "```sh mode=0700\necho hi\n```\n")
+	if mode := extractions[0].sourceCode.Mode; mode != 0700 {
+		t.Errorf("Expected mode 0700, got %04o", mode)
+	}
+
+	input := filepath.Join(t.TempDir(), "bad.md")
+	if err := os.WriteFile(input, []byte("```sh mode=rwx\necho hi\n```\n"), 0644); err != nil {
+		t.Fatalf("Failed to write markdown: %v", err)
+	}
+	setConfig(t, map[string]any{"input": input})
+	if _, err := extractAll(nil); err == nil || !strings.Contains(err.Error(), "bad.md:1:1: invalid mode attribute") {
+		t.Errorf("Expected the invalid mode to be reported with its position, got %v", err)
+	}
+}
+
+func TestExtractAllUntaggedAndEmpty(t *testing.T) {
+	markdown := "```\necho hi\n```\n\n```go filename=\"placeholder.go\"\n```\n"
+
+	if extractions := extractMarkdown(t, markdown); len(extractions) != 0 {
+		t.Fatalf("Expected untagged and empty blocks to be skipped by default, got %d", len(extractions))
+	}
+
+	setConfig(t, map[string]any{"include-untagged": true, "include-empty": true, "untagged-language": "bash"})
+	extractions := extractMarkdown(t, markdown)
+	if len(extractions) != 2 {
+		t.Fatalf("Expected 2 extractions, got %d", len(extractions))
+	}
+	if extractions[0].block.Language != "bash" || extractions[0].sourceCode.Filename != "sourcecode.sh" {
+		t.Errorf("Expected the untagged block to fall back to bash, got %q as %s", extractions[0].block.Language, extractions[0].sourceCode.Filename)
+	}
+	if extractions[1].sourceCode.Filename != "placeholder.go" || extractions[1].sourceCode.Content != "" {
+		t.Errorf("Unexpected empty block %+v", extractions[1].sourceCode)
+	}
+}
+
+func TestExtractAllIndented(t *testing.T) {
+	markdown := "Intro\n\n    #!/usr/bin/env python3\n    print(1)\n\nMore\n\n    plain text\n"
+
+	setConfig(t, map[string]any{"include-indented": true})
+	extractions := extractMarkdown(t, markdown)
+	if len(extractions) != 2 {
+		t.Fatalf("Expected 2 extractions, got %d", len(extractions))
+	}
+	if extractions[0].sourceCode.Filename != "sourcecode-0.py" || extractions[1].sourceCode.Filename != "sourcecode-1.txt" {
+		t.Errorf("Expected a detected and a fallback extension, got %s and %s", extractions[0].sourceCode.Filename, extractions[1].sourceCode.Filename)
+	}
+
+	setConfig(t, map[string]any{"indented-language": "bash", "lang": []string{"sh"}})
+	extractions = extractMarkdown(t, markdown)
+	if len(extractions) != 2 || !extractions[1].block.Indented || extractions[1].block.Language != "bash" {
+		t.Errorf("Expected the configured language to apply before --lang, got %+v", extractions)
+	}
+}
+
+func TestExtractAllDetectLanguage(t *testing.T) {
+	markdown := "```\npackage main\n\nfunc main() {}\n```\n\n```golang-ish\n<?php\necho 1;\n```\n\n```text\nHello\n```\n"
+
+	setConfig(t, map[string]any{"include-untagged": true})
+	extractions := extractMarkdown(t, markdown)
+	if len(extractions) != 3 || extractions[0].block.Language != "" || extractions[1].block.Language != "golang-ish" {
+		t.Fatalf("Expected no detection without --detect-language, got %+v", extractions)
+	}
+
+	setConfig(t, map[string]any{"include-untagged": true, "detect-language": true})
+	extractions = extractMarkdown(t, markdown)
+	if len(extractions) != 3 {
+		t.Fatalf("Expected 3 extractions, got %d", len(extractions))
+	}
+	for i, expected := range []string{"go", "php", "text"} {
+		if extractions[i].block.Language != expected {
+			t.Errorf("Expected block %d to be %s, got %s", i, expected, extractions[i].block.Language)
+		}
+	}
+	if extractions[0].sourceCode.Filename != "sourcecode-0.go" {
+		t.Errorf("Expected the detected extension, got %s", extractions[0].sourceCode.Filename)
+	}
+
+	setConfig(t, map[string]any{"include-untagged": true, "detect-language": true, "untagged-language": "bash"})
+	if extractions = extractMarkdown(t, markdown); extractions[0].block.Language != "bash" {
+		t.Errorf("Expected --untagged-language to win over detection, got %s", extractions[0].block.Language)
+	}
+}
+
+func TestNewWriterModesFromConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), ".codeblocks.yaml")
+	if err := os.WriteFile(config, []byte("file-mode: 0644\ndir-mode: 0750\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigFile(config)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unquoted octal numbers arrive as integers
+	setConfig(t, map[string]any{"file-mode": v.Get("file-mode"), "dir-mode": v.Get("dir-mode")})
+	writer, err := newWriter(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if writer.FileMode != 0644 || writer.DirMode != 0750 {
+		t.Errorf("Expected modes 0644 and 0750, got %04o and %04o", writer.FileMode, writer.DirMode)
+	}
+
+	setConfig(t, map[string]any{"file-mode": "600", "dir-mode": "0700"})
+	if writer, err = newWriter(t.TempDir()); err != nil || writer.FileMode != 0600 || writer.DirMode != 0700 {
+		t.Errorf("Expected modes from octal text, got %04o and %04o, %v", writer.FileMode, writer.DirMode, err)
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"slices"
+
+	"github.com/spandigitial/codeblocks/extract"
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// filterBlocks returns the blocks that every filter keeps, in order.
+func filterBlocks(blocks []model.FencedCodeBlock, filters []extract.Filter) []model.FencedCodeBlock {
+	var kept []model.FencedCodeBlock
+	for _, block := range blocks {
+		if slices.ContainsFunc(filters, func(filter extract.Filter) bool { return !filter(block) }) {
+			continue
+		}
+		kept = append(kept, block)
+	}
+	return kept
+}
+
+// languageFilter keeps blocks whose language matches one of include (all blocks if include is empty)
+// and none of exclude. Languages are matched through their aliases, see model.LanguageMatches.
+func languageFilter(include, exclude []string) extract.Filter {
+	matchesAny := func(language string, selectors []string) bool {
+		for _, selector := range selectors {
+			if model.LanguageMatches(language, selector) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(block model.FencedCodeBlock) bool {
+		if len(include) > 0 && !matchesAny(block.Language, include) {
+			return false
+		}
+		return !matchesAny(block.Language, exclude)
+	}
+}
+
+// sectionFilter keeps blocks nested under any of the selected sections, see extract.Section.
+func sectionFilter(selectors []string) (extract.Filter, error) {
+	var filters []extract.Filter
+	for _, selector := range selectors {
+		filter, err := extract.Section(selector)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return func(block model.FencedCodeBlock) bool {
+		for _, filter := range filters {
+			if filter(block) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
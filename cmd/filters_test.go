@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spandigitial/codeblocks/extract"
+)
+
+func TestLanguageFilter(t *testing.T) {
+	markdown := "```go\npackage main\n```\n\n" +
+		"```golang\npackage other\n```\n\n" +
+		"```bash\necho bash\n```\n\n" +
+		"```sh\necho sh\n```\n\n" +
+		"```zsh\necho zsh\n```\n\n" +
+		"```python\nprint(1)\n```\n"
+
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		languages []string
+	}{
+		{"include alias", []string{"golang"}, nil, []string{"go", "golang"}},
+		{"exclude alias", nil, []string{"shell"}, []string{"go", "golang", "python"}},
+		{"include and exclude", []string{"go", "python"}, []string{"python3"}, []string{"go", "golang"}},
+		{"include several", []string{"sh", "python"}, nil, []string{"bash", "sh", "zsh", "python"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codeBlocks, err := extract.New(extract.WithFilter(languageFilter(tt.include, tt.exclude))).Extract([]byte(markdown))
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if len(codeBlocks) != len(tt.languages) {
+				t.Fatalf("Expected %d code blocks, got %d", len(tt.languages), len(codeBlocks))
+			}
+			for i, codeBlock := range codeBlocks {
+				if codeBlock.Language != tt.languages[i] {
+					t.Errorf("Block %d: Expected language %s, got %s", i, tt.languages[i], codeBlock.Language)
+				}
+			}
+		})
+	}
+}
+
+func TestSectionFilter(t *testing.T) {
+	markdown := "# Install\n\n```bash\nmake install\n```\n\n" +
+		"# Usage\n\n## Examples\n\n```go\npackage example\n```\n\n" +
+		"# Changelog\n\n```diff\n+ added\n```\n"
+
+	filter, err := sectionFilter([]string{"Install", "Usage > Examples"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	codeBlocks, err := extract.New(extract.WithFilter(filter)).Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 2 || codeBlocks[0].Language != "bash" || codeBlocks[1].Language != "go" {
+		t.Errorf("Expected the bash and go blocks, got %+v", codeBlocks)
+	}
+
+	if _, err := sectionFilter([]string{"/(/"}); err == nil {
+		t.Error("Expected an error for an invalid regular expression")
+	}
+}
+
+func TestFiltersApplyAfterTangling(t *testing.T) {
+	markdown := "# Main\n\n```go chunk=main filename=\"main.go\"\npackage main\n\n<<helpers>>\n```\n\n" +
+		"```bash\ngo run .\n```\n\n" +
+		"# Appendix\n\n```go chunk=helpers\nfunc helper() {}\n```\n"
+
+	setConfig(t, map[string]any{"section": []string{"Main"}})
+	extractions := extractMarkdown(t, markdown)
+	if len(extractions) != 2 || extractions[0].sourceCode.Content != "package main\n\nfunc helper() {}\n" {
+		t.Fatalf("Expected a chunk outside the section to be expanded, got %+v", extractions)
+	}
+
+	setConfig(t, map[string]any{"section": []string{"Main"}, "lang": []string{"go"}})
+	extractions = extractMarkdown(t, markdown)
+	if len(extractions) != 1 || extractions[0].sourceCode.Filename != "main.go" || strings.Contains(extractions[0].sourceCode.Content, "<<") {
+		t.Errorf("Expected only the tangled main.go, got %+v", extractions)
+	}
+
+	setConfig(t, map[string]any{"section": []string{"Main"}, "lang": []string{"bash"}})
+	extractions = extractMarkdown(t, markdown)
+	if len(extractions) != 1 || extractions[0].block.Language != "bash" {
+		t.Errorf("Expected only the bash block, got %+v", extractions)
+	}
+}
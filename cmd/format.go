@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// blockRecord is the JSON representation of an extracted block.
+type blockRecord struct {
+	Language      string           `json:"language"`
+	Extension     string           `json:"extension"`
+	WholeFilename string           `json:"wholeFilename,omitempty"` // e.g. Dockerfile, for languages without an extension
+	Filename      string           `json:"filename"`
+	Attributes    model.Attributes `json:"attributes"`
+	Position      model.Position   `json:"position"`
+	Fence         *fenceRecord     `json:"fence,omitempty"`
+	Indented      bool             `json:"indented,omitempty"`
+	Headings      []model.Heading  `json:"headings"`
+	Content       string           `json:"content"`
+}
+
+// fenceRecord is the JSON representation of model.Fence, with the fence character as a string.
+type fenceRecord struct {
+	Char   string `json:"char"`
+	Length int    `json:"length"`
+	Indent int    `json:"indent"`
+}
+
+func newBlockRecord(e extraction) blockRecord {
+	record := blockRecord{
+		Language:      e.block.Language,
+		Extension:     e.extension,
+		WholeFilename: wholeFilename(e.block),
+		Filename:      e.sourceCode.Filename,
+		Attributes:    e.block.Attributes,
+		Position:      e.block.Position,
+		Indented:      e.block.Indented,
+		Headings:      e.block.Headings,
+		Content:       e.block.Content,
+	}
+	if e.block.Fence.Length > 0 {
+		record.Fence = &fenceRecord{Char: string(e.block.Fence.Char), Length: e.block.Fence.Length, Indent: e.block.Fence.Indent}
+	}
+	if record.Headings == nil {
+		record.Headings = []model.Heading{}
+	}
+	return record
+}
+
+// writeJSON lists the extractions on w, as an indented JSON array or, with ndjson, one compact object per line.
+func writeJSON(w io.Writer, extractions []extraction, ndjson bool) error {
+	encoder := json.NewEncoder(w)
+	if ndjson {
+		for _, extraction := range extractions {
+			if err := encoder.Encode(newBlockRecord(extraction)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	records := make([]blockRecord, 0, len(extractions))
+	for _, extraction := range extractions {
+		records = append(records, newBlockRecord(extraction))
+	}
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	markdown := "# Usage\n\n```go title=\"main.go\" {2}\npackage main\n```\n\n" +
+		"```python\nprint(1)\n```\n"
+	extractions := extractMarkdown(t, markdown)
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, extractions, true); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+
+	var records []blockRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record blockRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	first := records[0]
+	if first.Language != "go" || first.Extension != "go" || first.Filename != "main.go" {
+		t.Errorf("Unexpected first record %+v", first)
+	}
+	if first.Attributes.Values["title"] != "main.go" || len(first.Attributes.Lines) != 1 {
+		t.Errorf("Unexpected attributes %+v", first.Attributes)
+	}
+	if first.Position.StartLine != 3 || first.Position.EndLine != 5 || filepath.Base(first.Position.Path) != "doc.md" {
+		t.Errorf("Unexpected position %+v", first.Position)
+	}
+	if first.Fence == nil || first.Fence.Char != "`" || first.Fence.Length != 3 {
+		t.Errorf("Unexpected fence %+v", first.Fence)
+	}
+	if len(first.Headings) != 1 || first.Headings[0].Text != "Usage" {
+		t.Errorf("Unexpected headings %+v", first.Headings)
+	}
+	if first.Content != "package main\n" {
+		t.Errorf("Unexpected content %q", first.Content)
+	}
+
+	second := records[1]
+	if second.Filename != "sourcecode.py" || second.Extension != "py" {
+		t.Errorf("Unexpected second record %+v", second)
+	}
+}
+
+func TestWriteJSONArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, nil, false); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	if got := bytes.TrimSpace(buf.Bytes()); string(got) != "[]" {
+		t.Errorf("Expected an empty array, got %s", got)
+	}
+
+	extractions := extractMarkdown(t, "```go\npackage main\n```\n")
+	buf.Reset()
+	if err := writeJSON(&buf, extractions, false); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var records []blockRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(records) != 1 || records[0].Filename != "sourcecode.go" || len(records[0].Headings) != 0 {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestWriteJSONWholeFilename(t *testing.T) {
+	extractions := extractMarkdown(t, "```dockerfile\nFROM alpine\n```\n")
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, extractions, true); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var record blockRecord
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if record.Filename != "Dockerfile" || record.WholeFilename != "Dockerfile" || record.Extension != "" {
+		t.Errorf("Expected a whole filename without an extension, got %+v", record)
+	}
+}
+
+func TestWriteJSONPlannedFilenames(t *testing.T) {
+	extractions := extractMarkdown(t, "```go filename=\"m.go\"\npackage a\n```\n\n```go filename=\"m.go\"\npackage b\n```\n")
+	plan := newPlan(extractions, t.TempDir(), planOptions{onConflict: conflictSuffix})
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, plan.extractions(), false); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var records []blockRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(records) != 2 || records[0].Filename != "m.go" || records[1].Filename != "m-1.go" || records[1].Content != "package b\n" {
+		t.Errorf("Expected the filenames the run would write, got %+v", records)
+	}
+}
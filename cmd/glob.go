@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchGlob reports whether the slash-separated name matches pattern. Pattern segments use
+// path.Match syntax, and a ** segment matches zero or more whole segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], name[0]); err != nil || !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// hasMeta reports whether pattern contains glob metacharacters.
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globRoot returns the directory prefix of pattern that contains no metacharacters, "." if there is none.
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if hasMeta(segment) {
+			if i == 0 {
+				return "."
+			}
+			return strings.Join(segments[:i], "/")
+		}
+	}
+	return path.Dir(pattern)
+}
+
+// matchPattern matches an include or exclude pattern against a relative path. Like .gitignore,
+// a pattern without a slash matches the base name at any depth.
+func matchPattern(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		return matchGlob(pattern, path.Base(rel))
+	}
+	return matchGlob(pattern, rel)
+}
+
+// ignoreRule is a single line of a .gitignore file.
+type ignoreRule struct {
+	base    string // directory containing the .gitignore, relative to the walk root
+	prefix  string // for a .gitignore above the walk root, the path from its directory down to the root
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// gitignore accumulates the rules of the .gitignore files found while walking a directory tree.
+type gitignore struct {
+	rules []ignoreRule
+}
+
+// load reads the .gitignore in dir, if any. Rel is dir relative to the walk root.
+func (g *gitignore) load(dir, rel string) error {
+	return g.read(filepath.Join(dir, ".gitignore"), ignoreRule{base: rel})
+}
+
+// loadAncestors reads the .gitignore files in the directories above root, up to the top of the git
+// repository containing it, so that walking a subdirectory honours the same rules as walking the
+// repository. Outside a repository nothing is read.
+func (g *gitignore) loadAncestors(root string) error {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	var parents []string
+	var ancestors []ignoreRule
+	for current := dir; !isRepositoryRoot(current); {
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil
+		}
+		prefix, err := filepath.Rel(parent, dir)
+		if err != nil {
+			return err
+		}
+		parents = append(parents, parent)
+		ancestors = append(ancestors, ignoreRule{base: ".", prefix: filepath.ToSlash(prefix)})
+		current = parent
+	}
+
+	// Rules closer to the root are read last, so that they take precedence
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if err := g.read(filepath.Join(parents[i], ".gitignore"), ancestors[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isRepositoryRoot reports whether dir is the top of a git repository or worktree.
+func isRepositoryRoot(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// read adds the rules of a .gitignore file, if it exists, with the base and prefix of template.
+func (g *gitignore) read(name string, template ignoreRule) error {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := template
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.Contains(line, "/") {
+			// Patterns containing a slash are anchored to the .gitignore directory
+			rule.pattern = strings.TrimPrefix(line, "/")
+		} else {
+			rule.pattern = "**/" + line
+		}
+		g.rules = append(g.rules, rule)
+	}
+	return scanner.Err()
+}
+
+// ignored reports whether rel, relative to the walk root, is ignored. The last matching rule wins.
+func (g *gitignore) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "." {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if rule.prefix != "" {
+			name = rule.prefix + "/" + name
+		}
+		if matchGlob(rule.pattern, name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
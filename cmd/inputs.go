@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// markdownExtensions are the file extensions collected when walking a directory.
+var markdownExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+}
+
+// document is a Markdown input together with the subdirectory of the output directory its blocks are written to.
+type document struct {
+	// path is the Markdown file, empty for standard input.
+	path string
+	// outputDir is slash-separated and relative to the output directory, empty to write directly into it.
+	outputDir string
+}
+
+// inputOptions controls how input arguments are expanded into documents.
+type inputOptions struct {
+	include   []string // patterns a discovered file must match; in directories they replace the Markdown extension check
+	exclude   []string // patterns that drop a discovered file
+	gitignore bool     // honour .gitignore files while walking directories, and those above them in the repository
+}
+
+// resolveInputs expands paths, glob patterns (including **) and directories into documents.
+// No paths, or a single "-", reads standard input. A single plain file is written directly into the
+// output directory; otherwise each document gets its own subdirectory mirroring the input tree,
+// so that blocks from different documents cannot collide. With several arguments the subdirectories
+// of a directory or glob start with its root (docs/intro for docs/intro.md), so that files of the
+// same name under different roots stay apart. Include and exclude patterns apply to
+// files discovered through globs and directory walks; files named explicitly are always used.
+func resolveInputs(paths []string, options inputOptions) ([]document, error) {
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "-") {
+		return []document{{}}, nil
+	}
+
+	mirror := len(paths) > 1
+	// prefix returns the subdirectory of a file found under root, keeping roots apart when there are several
+	prefix := func(root, rel string) string {
+		if len(paths) == 1 {
+			return rel
+		}
+		return path.Join(localPath(root), rel)
+	}
+	var documents []document
+	seen := make(map[string]bool)
+	add := func(file, rel string) {
+		if seen[file] {
+			return
+		}
+		seen[file] = true
+		documents = append(documents, document{path: file, outputDir: strings.TrimSuffix(rel, path.Ext(rel))})
+	}
+
+	for _, arg := range paths {
+		if arg == "-" {
+			documents = append(documents, document{outputDir: "stdin"})
+			continue
+		}
+
+		pattern := filepath.ToSlash(filepath.Clean(arg))
+		if hasMeta(pattern) {
+			mirror = true
+			files, err := expandGlob(pattern, options)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+			for _, file := range files {
+				add(file.path, prefix(globRoot(pattern), file.rel))
+			}
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			mirror = true
+			files, err := walkDirectory(arg, func(rel string) bool {
+				return len(options.include) > 0 || markdownExtensions[strings.ToLower(path.Ext(rel))]
+			}, options)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				add(file.path, prefix(pattern, file.rel))
+			}
+			continue
+		}
+
+		add(arg, localPath(pattern))
+	}
+
+	if !mirror {
+		for i := range documents {
+			documents[i].outputDir = ""
+		}
+	}
+	return documents, nil
+}
+
+// localPath returns a slash-separated argument as a path relative to the output directory: the
+// path itself if it is local, or else its base name.
+func localPath(slashPath string) string {
+	if filepath.IsLocal(filepath.FromSlash(slashPath)) || slashPath == "." {
+		return slashPath
+	}
+	return path.Base(slashPath)
+}
+
+// discoveredFile is a file found by a glob or directory walk, with its path relative to the walk root.
+type discoveredFile struct {
+	path string
+	rel  string
+}
+
+// expandGlob walks the static prefix of pattern and returns the files matching it.
+func expandGlob(pattern string, options inputOptions) ([]discoveredFile, error) {
+	root := globRoot(pattern)
+	return walkDirectory(filepath.FromSlash(root), func(rel string) bool {
+		return matchGlob(pattern, path.Join(root, rel))
+	}, options)
+}
+
+// walkDirectory returns the files under root accepted by match and the include/exclude patterns,
+// skipping .git and, if enabled, anything ignored by a .gitignore in the tree or above it in the same
+// git repository. Files are returned in lexical walk order.
+func walkDirectory(root string, match func(rel string) bool, options inputOptions) ([]discoveredFile, error) {
+	var files []discoveredFile
+	var ignore gitignore
+	if options.gitignore {
+		if err := ignore.loadAncestors(root); err != nil {
+			return nil, err
+		}
+	}
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if rel != "." && options.gitignore && ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if options.gitignore {
+				return ignore.load(file, rel)
+			}
+			return nil
+		}
+
+		if options.gitignore && ignore.ignored(rel, false) {
+			return nil
+		}
+		if !match(rel) || !included(rel, options) {
+			return nil
+		}
+		files = append(files, discoveredFile{path: file, rel: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// included applies the include and exclude patterns to a discovered file.
+func included(rel string, options inputOptions) bool {
+	if len(options.include) > 0 {
+		found := false
+		for _, pattern := range options.include {
+			if matchPattern(pattern, rel) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, pattern := range options.exclude {
+		if matchPattern(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"docs/*.md", "docs/README.md", true},
+		{"docs/**/*.md", "docs/README.md", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"**/*.md", "a/b.md", true},
+		{"**", "a/b/c", true},
+		{"docs/**", "docs", true},
+		{"docs/**/*.md", "other/a.md", false},
+		{"a/?.md", "a/b.md", true},
+		{"a/[bc].md", "a/d.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if matched := matchGlob(tt.pattern, tt.name); matched != tt.expected {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, matched, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := map[string]string{
+		"docs/**/*.md": "docs",
+		"*.md":         ".",
+		"a/b/*.md":     "a/b",
+		"**/*.md":      ".",
+	}
+	for pattern, expected := range tests {
+		if root := globRoot(pattern); root != expected {
+			t.Errorf("globRoot(%q) = %q, want %q", pattern, root, expected)
+		}
+	}
+}
+
+// writeTree creates files relative to dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// chdir changes into dir for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestResolveInputs(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTree(t, ".", map[string]string{
+		"README.md":               "",
+		"docs/.gitignore":         "build/\n*.draft.md\n!keep.draft.md\n",
+		"docs/intro.md":           "",
+		"docs/guide/setup.mdx":    "",
+		"docs/guide/api.markdown": "",
+		"docs/guide/notes.txt":    "",
+		"docs/build/out.md":       "",
+		"docs/wip.draft.md":       "",
+		"docs/keep.draft.md":      "",
+		"docs/.git/HEAD.md":       "",
+		"other/intro.md":          "",
+	})
+
+	tests := []struct {
+		name     string
+		paths    []string
+		options  inputOptions
+		expected []document
+	}{
+		{"stdin", nil, inputOptions{}, []document{{}}},
+		{"dash", []string{"-"}, inputOptions{}, []document{{}}},
+		{"single file", []string{"README.md"}, inputOptions{}, []document{{path: "README.md"}}},
+		{
+			"multiple files",
+			[]string{"README.md", "docs/intro.md"},
+			inputOptions{},
+			[]document{{path: "README.md", outputDir: "README"}, {path: "docs/intro.md", outputDir: "docs/intro"}},
+		},
+		{
+			"directory with gitignore",
+			[]string{"docs"},
+			inputOptions{gitignore: true},
+			[]document{
+				{path: filepath.FromSlash("docs/guide/api.markdown"), outputDir: "guide/api"},
+				{path: filepath.FromSlash("docs/guide/setup.mdx"), outputDir: "guide/setup"},
+				{path: filepath.FromSlash("docs/intro.md"), outputDir: "intro"},
+				{path: filepath.FromSlash("docs/keep.draft.md"), outputDir: "keep.draft"},
+			},
+		},
+		{
+			"directory with exclude",
+			[]string{"docs"},
+			inputOptions{gitignore: true, exclude: []string{"guide/**"}},
+			[]document{
+				{path: filepath.FromSlash("docs/intro.md"), outputDir: "intro"},
+				{path: filepath.FromSlash("docs/keep.draft.md"), outputDir: "keep.draft"},
+			},
+		},
+		{
+			"directory with include",
+			[]string{"docs"},
+			inputOptions{gitignore: true, include: []string{"*.txt"}},
+			[]document{{path: filepath.FromSlash("docs/guide/notes.txt"), outputDir: "guide/notes"}},
+		},
+		{
+			"directories with files of the same name",
+			[]string{"docs", "other", "./README.md"},
+			inputOptions{gitignore: true, exclude: []string{"guide/**", "*.draft.md"}},
+			[]document{
+				{path: filepath.FromSlash("docs/intro.md"), outputDir: "docs/intro"},
+				{path: filepath.FromSlash("other/intro.md"), outputDir: "other/intro"},
+				{path: "./README.md", outputDir: "README"},
+			},
+		},
+		{
+			"globs with files of the same name",
+			[]string{"docs/*.md", "other/*.md"},
+			inputOptions{exclude: []string{"*.draft.md"}},
+			[]document{
+				{path: filepath.FromSlash("docs/intro.md"), outputDir: "docs/intro"},
+				{path: filepath.FromSlash("other/intro.md"), outputDir: "other/intro"},
+			},
+		},
+		{
+			"recursive glob without gitignore",
+			[]string{"docs/**/*.md"},
+			inputOptions{},
+			[]document{
+				{path: filepath.FromSlash("docs/build/out.md"), outputDir: "build/out"},
+				{path: filepath.FromSlash("docs/intro.md"), outputDir: "intro"},
+				{path: filepath.FromSlash("docs/keep.draft.md"), outputDir: "keep.draft"},
+				{path: filepath.FromSlash("docs/wip.draft.md"), outputDir: "wip.draft"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := resolveInputs(tt.paths, tt.options)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(documents, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, documents)
+			}
+		})
+	}
+}
+
+func TestResolveInputsAncestorGitignore(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTree(t, ".", map[string]string{
+		".gitignore":               "*.md\n",
+		"repo/.git/HEAD":           "",
+		"repo/.gitignore":          "*.draft.md\n/docs/build/\n",
+		"repo/docs/.gitignore":     "!keep.draft.md\n",
+		"repo/docs/intro.md":       "",
+		"repo/docs/wip.draft.md":   "",
+		"repo/docs/keep.draft.md":  "",
+		"repo/docs/build/out.md":   "",
+		"repo/docs/guide/build.md": "",
+	})
+
+	// The repository's .gitignore applies below it, but not the one outside the repository
+	documents, err := resolveInputs([]string{"repo/docs"}, inputOptions{gitignore: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []document{
+		{path: filepath.FromSlash("repo/docs/guide/build.md"), outputDir: "guide/build"},
+		{path: filepath.FromSlash("repo/docs/intro.md"), outputDir: "intro"},
+		{path: filepath.FromSlash("repo/docs/keep.draft.md"), outputDir: "keep.draft"},
+	}
+	if !reflect.DeepEqual(documents, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, documents)
+	}
+
+	documents, err = resolveInputs([]string{"repo/docs/**/*.md"}, inputOptions{gitignore: true})
+	if err != nil || len(documents) != 3 {
+		t.Errorf("Expected globs to honour the repository's .gitignore too, got %+v, %v", documents, err)
+	}
+}
+
+func TestResolveInputsErrors(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	if _, err := resolveInputs([]string{"missing.md"}, inputOptions{}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := resolveInputs([]string{"*.md"}, inputOptions{}); err == nil {
+		t.Error("Expected an error for a glob without matches")
+	}
+}
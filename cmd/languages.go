@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// registerLanguages adds the languages: section of the config file, a map of language names or
+// aliases to file extensions, to registry. Entries override built-in languages of the same name.
+func registerLanguages(registry *model.LanguageRegistry, languages map[string]string) error {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names) // report the first invalid entry consistently
+	for _, name := range names {
+		if err := registry.Register(name, languages[name]); err != nil {
+			return fmt.Errorf("invalid languages entry: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/spf13/viper"
+)
+
+func TestRegisterLanguages(t *testing.T) {
+	config := filepath.Join(t.TempDir(), ".codeblocks.yaml")
+	if err := os.WriteFile(config, []byte("languages:\n  terraform: tf\n  nix: .nix\n  yaml: yml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigFile(config)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := model.NewLanguageRegistry()
+	if err := registerLanguages(registry, v.GetStringMapString("languages")); err != nil {
+		t.Fatal(err)
+	}
+	for language, expected := range map[string]string{"terraform": "tf", "nix": "nix", "yaml": "yml", "go": "go"} {
+		if extension := registry.Extension(language); extension != expected {
+			t.Errorf("Expected %s to map to %s, got %s", language, expected, extension)
+		}
+	}
+
+	if registry.Extension("yml") != "yml" || !registry.Matches("yml", "yaml") || !registry.Matches("Yaml", "yml") {
+		t.Error("Expected the yml alias to follow the overridden yaml language")
+	}
+	if info, _ := registry.Info("yaml"); len(info.Aliases) != 1 || info.Aliases[0] != "yml" {
+		t.Errorf("Expected yaml to keep its aliases, got %+v", info)
+	}
+
+	err := registerLanguages(registry, map[string]string{"zig": "zig", "bad": ""})
+	if err == nil || !strings.Contains(err.Error(), "invalid languages entry") {
+		t.Errorf("Expected an invalid entry to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// manifestName is the file in the output directory that records the files codeblocks generated.
+const manifestName = ".codeblocks-manifest.json"
+
+// manifestVersion is written to new manifests; readers reject newer versions.
+const manifestVersion = 1
+
+// manifest lists the files a run generated, so that a later run can tell its own files apart from
+// files it did not create.
+type manifest struct {
+	Version int            `json:"version"`
+	Files   []manifestFile `json:"files"`
+}
+
+// manifestFile is a generated file together with the block it came from.
+type manifestFile struct {
+	Path   string `json:"path"`             // slash-separated, relative to the output directory
+	Source string `json:"source,omitempty"` // Markdown document, empty for standard input
+	Line   int    `json:"line,omitempty"`   // line of the block's opening fence
+	Hash   string `json:"hash"`             // see fileHash
+}
+
+// fileHash returns the SHA-256 of content in the form sha256:<hex>.
+func fileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readManifest reads the manifest in outputDirectory, returning an empty manifest if there is none.
+func readManifest(outputDirectory string) (manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outputDirectory, manifestName))
+	if os.IsNotExist(err) {
+		return manifest{Version: manifestVersion}, nil
+	}
+	if err != nil {
+		return manifest{}, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, fmt.Errorf("invalid %s: %w", manifestName, err)
+	}
+	if m.Version > manifestVersion {
+		return manifest{}, fmt.Errorf("%s has version %d, this codeblocks understands up to %d", manifestName, m.Version, manifestVersion)
+	}
+	return m, nil
+}
+
+// write saves the manifest into outputDirectory, replacing any previous one atomically.
+func (m manifest) write(outputDirectory string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer := model.Writer{Root: outputDirectory, Output: io.Discard}
+	_, err = writer.Write(model.SourceCode{Filename: manifestName, Content: string(data) + "\n"})
+	return err
+}
+
+// lookup returns the record for path, if any.
+func (m manifest) lookup(path string) (manifestFile, bool) {
+	for _, file := range m.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return manifestFile{}, false
+}
+
+// newManifestFile records the file an extraction is written to.
+func newManifestFile(e extraction) manifestFile {
+	return manifestFile{
+		Path:   e.sourceCode.Filename,
+		Source: e.block.Position.Path,
+		Line:   e.block.Position.StartLine,
+		Hash:   fileHash([]byte(e.sourceCode.Content)),
+	}
+}
+
+// source returns the block a file was generated from as path:line.
+func (f manifestFile) source() string {
+	source := f.Source
+	if source == "" {
+		source = "<stdin>"
+	}
+	return fmt.Sprintf("%s:%d", source, f.Line)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generate runs the command on markdown with outputDirectory and --prune, failing on conflicts,
+// and returns what it did and the manifest it recorded.
+func generate(t *testing.T, markdown, outputDirectory string, prune bool) (runResult, manifest) {
+	t.Helper()
+	return generateWith(t, markdown, outputDirectory, prune, conflictError)
+}
+
+// generateWith is generate with the given --on-conflict policy.
+func generateWith(t *testing.T, markdown, outputDirectory string, prune bool, onConflict string) (runResult, manifest) {
+	t.Helper()
+	setInput(t, markdown)
+	setConfig(t, map[string]any{
+		"output-directory": outputDirectory,
+		"prune":            prune,
+		"on-conflict":      onConflict,
+		"format":           "files",
+		"dry-run":          false,
+		"no-clobber":       false,
+	})
+	result, err := run(nil, &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+
+	m, err := readManifest(outputDirectory)
+	if err != nil {
+		t.Fatalf("Failed to read manifest back: %v", err)
+	}
+	return result, m
+}
+
+func manifestPaths(m manifest) []string {
+	var paths []string
+	for _, file := range m.Files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestManifest(t *testing.T) {
+	outputDirectory := t.TempDir()
+	_, m := generate(t, "```go filename=\"pkg/a.go\"\npackage a\n```\n\n```go\npackage b\n```\n", outputDirectory, false)
+
+	if len(m.Files) != 2 {
+		t.Fatalf("Expected 2 files in the manifest, got %+v", m.Files)
+	}
+	file := m.Files[0]
+	if file.Path != "pkg/a.go" || filepath.Base(file.Source) != "doc.md" || file.Line != 1 {
+		t.Errorf("Unexpected record %+v", file)
+	}
+	if file.Hash != fileHash([]byte("package a\n")) || !strings.HasPrefix(file.Hash, "sha256:") {
+		t.Errorf("Unexpected hash %s", file.Hash)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	outputDirectory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDirectory, "notes.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatalf("Failed to write unrelated file: %v", err)
+	}
+	generate(t, "```go filename=\"old/gone.go\"\npackage gone\n```\n\n"+
+		"```go filename=\"edited.go\"\npackage edited\n```\n\n"+
+		"```go filename=\"kept.go\"\npackage kept\n```\n", outputDirectory, false)
+	if err := os.WriteFile(filepath.Join(outputDirectory, "edited.go"), []byte("package edited // by hand\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit file: %v", err)
+	}
+
+	// Without --prune stale files stay and remain tracked
+	result, m := generate(t, "```go filename=\"kept.go\"\npackage kept\n```\n", outputDirectory, false)
+	if len(result.plan.stale) != 2 {
+		t.Fatalf("Expected 2 stale files, got %+v", result.plan.stale)
+	}
+	if paths := strings.Join(manifestPaths(m), " "); paths != "kept.go old/gone.go" {
+		t.Errorf("Expected kept.go and old/gone.go in the manifest, got %s", paths)
+	}
+	if !fileExists(filepath.Join(outputDirectory, "old", "gone.go")) {
+		t.Error("Expected old/gone.go to survive a run without --prune")
+	}
+
+	_, m = generate(t, "```go filename=\"kept.go\"\npackage kept\n```\n", outputDirectory, true)
+	if fileExists(filepath.Join(outputDirectory, "old")) {
+		t.Error("Expected old/gone.go and its emptied directory to be pruned")
+	}
+	for _, name := range []string{"edited.go", "kept.go", "notes.txt"} {
+		if !fileExists(filepath.Join(outputDirectory, name)) {
+			t.Errorf("Expected %s to be left alone", name)
+		}
+	}
+	if paths := strings.Join(manifestPaths(m), " "); paths != "kept.go" {
+		t.Errorf("Expected only kept.go in the manifest, got %s", paths)
+	}
+}
+
+func TestManifestOverwritePolicy(t *testing.T) {
+	outputDirectory := t.TempDir()
+	generateWith(t, "```go filename=\"main.go\"\npackage a\n```\n", outputDirectory, false, conflictOverwrite)
+
+	// A later block replaces the recorded one; the manifest must follow the block that is written
+	_, m := generateWith(t, "```go filename=\"main.go\"\npackage a\n```\n\n```go filename=\"main.go\"\npackage b\n```\n", outputDirectory, false, conflictOverwrite)
+	if len(m.Files) != 1 || m.Files[0].Line != 5 || m.Files[0].Hash != fileHash([]byte("package b\n")) {
+		t.Fatalf("Expected the manifest to record the block that won, got %+v", m.Files)
+	}
+
+	result, _ := generateWith(t, "```go filename=\"other.go\"\npackage other\n```\n", outputDirectory, true, conflictOverwrite)
+	if stale := result.plan.stale; len(stale) != 1 || stale[0].action != actionPrune || fileExists(filepath.Join(outputDirectory, "main.go")) {
+		t.Errorf("Expected main.go to be pruned as a generated file, got %+v", stale)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	outputDirectory := t.TempDir()
+	markdown := "```go filename=\"a.go\"\npackage a\n```\n\n```go filename=\"b.go\"\npackage b\n```\n"
+	result, m := generate(t, markdown, outputDirectory, false)
+	if result.written != 2 || result.unchanged != 0 || result.skipped != 0 || len(m.Files) != 2 {
+		t.Errorf("Expected 2 files written and recorded, got %+v and %+v", result, m.Files)
+	}
+
+	result, _ = generate(t, markdown, outputDirectory, false)
+	if result.written != 0 || result.unchanged != 2 {
+		t.Errorf("Expected 2 unchanged files on a second run, got %+v", result)
+	}
+
+	setConfig(t, map[string]any{"no-clobber": true})
+	setInput(t, markdown)
+	result, err := run(nil, &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil || result.skipped != 2 {
+		t.Errorf("Expected 2 files skipped with --no-clobber, got %+v, %v", result, err)
+	}
+
+	// A run without blocks and without a previous manifest leaves no manifest behind
+	empty := t.TempDir()
+	generate(t, "No code here.\n", empty, false)
+	if fileExists(filepath.Join(empty, manifestName)) {
+		t.Error("Expected no manifest for a run that generated nothing")
+	}
+
+	setInput(t, "```go filename=\"c.go\"\npackage a\n```\n\n```go filename=\"c.go\"\npackage b\n```\n")
+	setConfig(t, map[string]any{"on-conflict": conflictError, "no-clobber": false})
+	if _, err := run(nil, &bytes.Buffer{}, &bytes.Buffer{}); err == nil || fileExists(filepath.Join(outputDirectory, "c.go")) {
+		t.Errorf("Expected a conflict to fail the run before writing, got %v", err)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	outputDirectory := t.TempDir()
+	markdown := "```go filename=\"a.go\"\npackage a\n```\n"
+	generate(t, markdown, outputDirectory, false)
+
+	var diff bytes.Buffer
+	if err := runCheck(nil, &diff, &bytes.Buffer{}); err != nil || diff.Len() != 0 {
+		t.Errorf("Expected generated files to be up to date, got %v:\n%s", err, diff.String())
+	}
+
+	setInput(t, "```go filename=\"a.go\"\npackage changed\n```\n")
+	err := runCheck(nil, &diff, &bytes.Buffer{})
+	if err == nil || err.Error() != "1 of 1 files out of date" || !strings.Contains(diff.String(), "+package changed") {
+		t.Errorf("Expected check to fail with a diff, got %v:\n%s", err, diff.String())
+	}
+}
+
+func TestReadManifestRejectsNewerVersion(t *testing.T) {
+	outputDirectory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDirectory, manifestName), []byte(`{"version": 99, "files": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := readManifest(outputDirectory); err == nil {
+		t.Error("Expected a manifest from a newer version to be rejected")
+	}
+}
+
+func TestPruneRelativeOutputDirectory(t *testing.T) {
+	chdir(t, t.TempDir())
+	generate(t, "```go filename=\"a/b/x.go\"\npackage x\n```\n", ".", false)
+
+	generate(t, "```go filename=\"kept.go\"\npackage kept\n```\n", ".", true)
+	if fileExists("a") {
+		t.Error("Expected the directories emptied by pruning to be removed under -o .")
+	}
+	if !fileExists("kept.go") {
+		t.Error("Expected kept.go to be written")
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// nameData is the data available to a --name-template.
+type nameData struct {
+	Index         int    // position of the block among all extracted blocks
+	LanguageIndex int    // position of the block among blocks of the same language
+	Language      string // language from the info string
+	Ext           string // resolved file extension, empty for languages named by a whole filename
+	WholeFilename string // e.g. Dockerfile for languages known by a whole filename, else empty
+	Heading       string // slug of the innermost enclosing heading
+	Line          int    // line of the opening fence
+	InputBase     string // input filename without directory or extension, "stdin" for standard input
+	Hash          string // first 8 hex digits of the SHA-256 of the content
+}
+
+// parseNameTemplate parses and validates a --name-template by rendering it against sample data,
+// so that unknown fields and syntax errors are reported before anything is extracted.
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template %q: %w", text, err)
+	}
+	sample := nameData{Language: "go", Ext: "go", Heading: "usage", Line: 1, InputBase: "README", Hash: "0123abcd"}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, sample); err != nil {
+		return nil, fmt.Errorf("invalid name template %q: %w", text, err)
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return nil, fmt.Errorf("invalid name template %q: produces an empty filename", text)
+	}
+	return tmpl, nil
+}
+
+// blockNamer assigns output filenames to blocks in document order.
+// A filename attribute on the fence always wins; otherwise the name template is used if set.
+// Without a template, languages known by a whole filename are named after it, numbered from the
+// second block on (Dockerfile, Dockerfile.2), and other blocks fall back to <prefix>-<index>.<ext>,
+// or <prefix>.<ext> when only one block needs a name.
+type blockNamer struct {
+	template  *template.Template
+	prefix    string
+	extension string // user-specified extension overriding auto-detection, empty to detect
+	inputBase string
+	unnamed   int // number of blocks named <prefix>-<index>.<ext>
+
+	index          int
+	unnamedIndex   int
+	languageIndex  map[string]int
+	wholeFilenames map[string]int // blocks named after each whole filename so far
+}
+
+func newBlockNamer(tmpl *template.Template, prefix, extension, input string, codeBlocks []model.FencedCodeBlock) *blockNamer {
+	inputBase := "stdin"
+	if input != "" {
+		inputBase = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	}
+	n := &blockNamer{
+		template:       tmpl,
+		prefix:         prefix,
+		extension:      extension,
+		inputBase:      inputBase,
+		languageIndex:  make(map[string]int),
+		wholeFilenames: make(map[string]int),
+	}
+	for _, codeBlock := range codeBlocks {
+		if _, whole := n.wholeFilename(codeBlock); codeBlock.Attributes.Filename() == "" && !whole {
+			n.unnamed++
+		}
+	}
+	return n
+}
+
+// next returns the filename for the next block. Blocks must be passed in document order.
+func (n *blockNamer) next(block model.FencedCodeBlock) (string, error) {
+	language := strings.ToLower(block.Language)
+	data := nameData{
+		Index:         n.index,
+		LanguageIndex: n.languageIndex[language],
+		Language:      block.Language,
+		Ext:           n.extensionFor(block),
+		WholeFilename: wholeFilename(block),
+		Line:          block.Position.StartLine,
+		InputBase:     n.inputBase,
+		Hash:          contentHash(block.Content),
+	}
+	if heading, found := block.Heading(); found {
+		data.Heading = heading.Slug()
+	}
+	n.index++
+	n.languageIndex[language]++
+
+	if filename := block.Attributes.Filename(); filename != "" {
+		return filename, nil
+	}
+
+	if n.template != nil {
+		var buf bytes.Buffer
+		if err := n.template.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("name template failed for block at line %d: %w", block.Position.StartLine, err)
+		}
+		filename := strings.TrimSpace(buf.String())
+		if filename == "" {
+			return "", fmt.Errorf("name template produced an empty filename for block at line %d", block.Position.StartLine)
+		}
+		return filename, nil
+	}
+
+	if filename, whole := n.wholeFilename(block); whole {
+		n.wholeFilenames[filename]++
+		if count := n.wholeFilenames[filename]; count > 1 {
+			return fmt.Sprintf("%s.%d", filename, count), nil
+		}
+		return filename, nil
+	}
+	if n.unnamed == 1 {
+		return fmt.Sprintf("%s.%s", n.prefix, data.Ext), nil
+	}
+	n.unnamedIndex++
+	return fmt.Sprintf("%s-%d.%s", n.prefix, n.unnamedIndex-1, data.Ext), nil
+}
+
+// extensionFor determines the extension: user override > language detection > default fallback.
+// Languages known by a whole filename, such as Dockerfile, have no extension.
+func (n *blockNamer) extensionFor(block model.FencedCodeBlock) string {
+	if n.extension != "" {
+		return n.extension
+	}
+	if wholeFilename(block) != "" {
+		return ""
+	}
+	return model.DefaultLanguages.Extension(block.Language)
+}
+
+// wholeFilename returns the filename of a block whose language is known by a whole filename, such
+// as Dockerfile, unless a template or --extension decides the name.
+func (n *blockNamer) wholeFilename(block model.FencedCodeBlock) (string, bool) {
+	if n.template != nil || n.extension != "" {
+		return "", false
+	}
+	filename := wholeFilename(block)
+	return filename, filename != ""
+}
+
+// wholeFilename returns the whole filename of the language of block, such as Dockerfile, if it has one.
+func wholeFilename(block model.FencedCodeBlock) string {
+	info, found := model.LookupLanguage(block.Language)
+	if !found {
+		return ""
+	}
+	filename, _ := info.WholeFilename()
+	return filename
+}
+
+// contentHash returns the first 8 hex digits of the SHA-256 of content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])[:8]
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNameTemplateValidation(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{"{{.InputBase}}-{{.Index}}.{{.Ext}}", true},
+		{"{{.Heading}}/{{.LanguageIndex}}.{{.Ext}}", true},
+		{"{{.Language}}-{{.Line}}-{{.Hash}}.{{.Ext}}", true},
+		{"{{.Unknown}}.txt", false},     // Unknown field
+		{"{{.Index", false},             // Syntax error
+		{"{{if false}}x{{end}}", false}, // Empty filename
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := parseNameTemplate(tt.template)
+			if tt.valid && err != nil {
+				t.Errorf("Expected template to be valid, got %v", err)
+			}
+			if !tt.valid {
+				if err == nil {
+					t.Error("Expected template to be rejected")
+				} else if !strings.Contains(err.Error(), "invalid name template") {
+					t.Errorf("Expected a clear error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockNamerTemplate(t *testing.T) {
+	markdown := "# Getting Started\n\n" +
+		"```go\npackage main\n```\n\n" +
+		"## Python Setup\n\n" +
+		"```python\nprint('a')\n```\n\n" +
+		"```golang\npackage other\n```\n\n" +
+		"```python filename=\"setup.py\"\nprint('b')\n```\n"
+
+	codeBlocks := extractCodeBlocks(t, markdown)
+	if len(codeBlocks) != 4 {
+		t.Fatalf("Expected 4 code blocks, got %d", len(codeBlocks))
+	}
+
+	tmpl, err := parseNameTemplate("{{.InputBase}}/{{.Heading}}-{{.Language}}{{.LanguageIndex}}-{{.Index}}-L{{.Line}}.{{.Ext}}")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	namer := newBlockNamer(tmpl, "sourcecode", "", "docs/guide.md", codeBlocks)
+	expected := []string{
+		"guide/getting-started-go0-0-L3.go",
+		"guide/python-setup-python0-1-L9.py",
+		"guide/python-setup-golang0-2-L13.go",
+		"setup.py", // Filename attribute wins over the template
+	}
+
+	for i, codeBlock := range codeBlocks {
+		filename, err := namer.next(codeBlock)
+		if err != nil {
+			t.Fatalf("Block %d: unexpected error: %v", i, err)
+		}
+		if filename != expected[i] {
+			t.Errorf("Block %d: Expected filename %s, got %s", i, expected[i], filename)
+		}
+	}
+}
+
+func TestBlockNamerHash(t *testing.T) {
+	codeBlocks := extractCodeBlocks(t, "```go\npackage main\n```\n")
+
+	tmpl, err := parseNameTemplate("{{.Hash}}.{{.Ext}}")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	filename, err := newBlockNamer(tmpl, "sourcecode", "", "", codeBlocks).next(codeBlocks[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filename != contentHash("package main\n")+".go" || len(filename) != len("01234567.go") {
+		t.Errorf("Unexpected hashed filename %s", filename)
+	}
+}
+
+func TestBlockNamerDefaultScheme(t *testing.T) {
+	markdown := "```go\npackage main\n```\n\n" +
+		"```go filename=\"main.go\"\npackage main\n```\n\n" +
+		"```python\nprint('a')\n```\n"
+
+	codeBlocks := extractCodeBlocks(t, markdown)
+	namer := newBlockNamer(nil, "sourcecode", "", "", codeBlocks)
+	expected := []string{"sourcecode-0.go", "main.go", "sourcecode-1.py"}
+
+	for i, codeBlock := range codeBlocks {
+		filename, err := namer.next(codeBlock)
+		if err != nil {
+			t.Fatalf("Block %d: unexpected error: %v", i, err)
+		}
+		if filename != expected[i] {
+			t.Errorf("Block %d: Expected filename %s, got %s", i, expected[i], filename)
+		}
+	}
+}
+
+func TestBlockNamerWholeFilenames(t *testing.T) {
+	markdown := "```dockerfile\nFROM alpine\n```\n\n" +
+		"```go\npackage main\n```\n\n" +
+		"```Docker\nFROM golang\n```\n\n" +
+		"```make\nall:\n```\n\n" +
+		"```dockerfile\nFROM scratch\n```\n"
+
+	codeBlocks := extractCodeBlocks(t, markdown)
+	namer := newBlockNamer(nil, "sourcecode", "", "", codeBlocks)
+	expected := []string{"Dockerfile", "sourcecode.go", "Dockerfile.2", "Makefile", "Dockerfile.3"}
+	for i, codeBlock := range codeBlocks {
+		filename, err := namer.next(codeBlock)
+		if err != nil {
+			t.Fatalf("Block %d: unexpected error: %v", i, err)
+		}
+		if filename != expected[i] {
+			t.Errorf("Block %d: Expected filename %s, got %s", i, expected[i], filename)
+		}
+	}
+
+	namer = newBlockNamer(nil, "sourcecode", "txt", "", codeBlocks)
+	if filename, _ := namer.next(codeBlocks[0]); filename != "sourcecode-0.txt" {
+		t.Errorf("Expected --extension to override the whole filename, got %s", filename)
+	}
+}
+
+func TestBlockNamerTemplateWholeFilename(t *testing.T) {
+	codeBlocks := extractCodeBlocks(t, "```dockerfile\nFROM alpine\n```\n\n```go\npackage main\n```\n")
+
+	tmpl, err := parseNameTemplate("{{.Index}}/{{or .WholeFilename (print \"main.\" .Ext)}}")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	namer := newBlockNamer(tmpl, "sourcecode", "", "", codeBlocks)
+	for i, expected := range []string{"0/Dockerfile", "1/main.go"} {
+		if filename, err := namer.next(codeBlocks[i]); err != nil || filename != expected {
+			t.Errorf("Block %d: Expected filename %s, got %s, %v", i, expected, filename, err)
+		}
+	}
+	if ext := namer.extensionFor(codeBlocks[0]); ext != "" {
+		t.Errorf("Expected a Dockerfile to have no extension, got %q", ext)
+	}
+}
@@ -0,0 +1,389 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// Plan actions.
+const (
+	actionCreate    = "create"    // the destination does not exist yet
+	actionOverwrite = "overwrite" // the destination exists and will be replaced
+	actionCollision = "collision" // another block in the run resolves to the same destination
+	actionSkip      = "skip"      // the block is not written, see the entry's note
+	actionAppend    = "append"    // the block is concatenated to an earlier block with the same destination
+	actionReplace   = "replace"   // the block is replaced by a later block with the same destination
+	actionUnsafe    = "unsafe"    // the filename leaves the output directory
+	actionPrune     = "prune"     // a previously generated file is no longer produced and will be deleted
+	actionStale     = "stale"     // a previously generated file is no longer produced and is left alone
+)
+
+// Conflict policies for blocks resolving to the same destination.
+const (
+	conflictError     = "error"     // fail the run before anything is written
+	conflictOverwrite = "overwrite" // the last block wins
+	conflictSuffix    = "suffix"    // later blocks get a numeric suffix, e.g. main-1.go
+	conflictSkip      = "skip"      // the first block wins
+	conflictConcat    = "concat"    // the blocks are concatenated in document order
+)
+
+// planOptions controls how conflicts are resolved.
+type planOptions struct {
+	onConflict string // one of the conflict policies; the zero value is treated as conflictError
+	separator  string // inserted between concatenated blocks
+	noClobber  bool   // skip destinations that already exist on disk
+}
+
+// validateConflictPolicy checks an --on-conflict value.
+func validateConflictPolicy(policy string) error {
+	switch policy {
+	case conflictError, conflictOverwrite, conflictSuffix, conflictSkip, conflictConcat:
+		return nil
+	}
+	return fmt.Errorf("unknown conflict policy %q, expected concat, error, overwrite, suffix or skip", policy)
+}
+
+// planEntry is a file the run would write and what would happen to it.
+type planEntry struct {
+	extraction
+	destination string // path on disk
+	action      string
+	note        string // why the entry collides or is skipped
+}
+
+// staleFile is a file recorded in the previous manifest that the run no longer generates.
+type staleFile struct {
+	manifestFile
+	destination string
+	action      string // actionPrune or actionStale
+	note        string
+	tracked     bool // whether the file stays in the manifest, so a later --prune can still remove it
+}
+
+// plan resolves where every extraction would be written, in document order.
+type plan struct {
+	entries []planEntry
+	stale   []staleFile
+}
+
+// newPlan resolves the destination of every extraction under outputDirectory, rejecting filenames
+// that would leave it (see model.ResolvePath), and applies the
+// conflict policy to blocks resolving to the same destination, across the whole run, so that
+// conflicts are known before anything is written. It does not modify the filesystem.
+func newPlan(extractions []extraction, outputDirectory string, options planOptions) plan {
+	var p plan
+	claimed := make(map[string]int) // destination to index of the entry holding it
+	for _, e := range extractions {
+		entry := planEntry{extraction: e, action: actionCreate}
+		destination, err := model.ResolvePath(outputDirectory, e.sourceCode.Filename)
+		if err != nil {
+			entry.action = actionUnsafe
+			entry.note = err.Error()
+			p.entries = append(p.entries, entry)
+			continue
+		}
+		entry.destination = destination
+
+		if first, found := claimed[entry.destination]; found {
+			holder := &p.entries[first]
+			switch options.onConflict {
+			case conflictOverwrite:
+				holder.action = actionReplace
+				holder.note = "replaced by " + entry.block.Position.String()
+			case conflictSuffix:
+				// Only the base name changes, so the suffixed path stays as safe as the original
+				for i := 1; found; i++ {
+					entry.sourceCode.Filename = suffixed(e.sourceCode.Filename, i)
+					entry.destination = filepath.Join(outputDirectory, filepath.FromSlash(entry.sourceCode.Filename))
+					_, found = claimed[entry.destination]
+				}
+			case conflictSkip:
+				entry.action = actionSkip
+				entry.note = "same file as " + holder.block.Position.String()
+			case conflictConcat:
+				holder.sourceCode.Content += options.separator + e.sourceCode.Content
+				entry.action = actionAppend
+				entry.note = "appended to " + holder.block.Position.String()
+			default:
+				entry.action = actionCollision
+				entry.note = "conflicts with " + holder.block.Position.String()
+			}
+		}
+
+		if entry.action == actionCreate {
+			claimed[entry.destination] = len(p.entries)
+			if _, err := os.Lstat(entry.destination); err == nil {
+				if options.noClobber {
+					entry.action = actionSkip
+					entry.note = "file exists"
+				} else {
+					entry.action = actionOverwrite
+				}
+			}
+		}
+		p.entries = append(p.entries, entry)
+	}
+	return p
+}
+
+// extractions returns the extractions of the plan in order, with the filenames it resolved.
+func (p plan) extractions() []extraction {
+	extractions := make([]extraction, len(p.entries))
+	for i, entry := range p.entries {
+		extractions[i] = entry.extraction
+	}
+	return extractions
+}
+
+// addStale records the files of the previous manifest that the plan no longer generates. With prune
+// they are deleted, but only while their content still matches the manifest: a file that was
+// edited since it was generated, or that does not resolve inside the output directory, is never touched.
+func (p *plan) addStale(previous manifest, outputDirectory string, prune bool) {
+	generated := make(map[string]bool)
+	for _, entry := range p.entries {
+		generated[entry.sourceCode.Filename] = true
+	}
+
+	for _, file := range previous.Files {
+		if generated[file.Path] {
+			continue
+		}
+		destination, err := model.ResolvePath(outputDirectory, file.Path)
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(destination)
+		if err != nil {
+			// Already gone, or not ours to inspect
+			continue
+		}
+
+		stale := staleFile{manifestFile: file, destination: destination, action: actionStale}
+		switch {
+		case fileHash(content) != file.Hash:
+			stale.note = "modified since it was generated"
+		case prune:
+			stale.action = actionPrune
+			stale.note = "no longer generated"
+		default:
+			stale.note = "no longer generated, see --prune"
+			stale.tracked = true
+		}
+		p.stale = append(p.stale, stale)
+	}
+}
+
+// manifest returns the manifest describing the output directory once the plan has been carried out.
+// Files skipped because they already exist stay recorded if the previous manifest had them. Written
+// files are recorded first, so that a skipped block never shadows the block written to its file.
+func (p plan) manifest(previous manifest) manifest {
+	m := manifest{Version: manifestVersion, Files: []manifestFile{}}
+	recorded := make(map[string]bool)
+	for _, entry := range p.entries {
+		if (entry.action == actionCreate || entry.action == actionOverwrite) && !recorded[entry.sourceCode.Filename] {
+			m.Files = append(m.Files, newManifestFile(entry.extraction))
+			recorded[entry.sourceCode.Filename] = true
+		}
+	}
+	for _, entry := range p.entries {
+		if entry.action != actionSkip || recorded[entry.sourceCode.Filename] {
+			continue
+		}
+		if file, found := previous.lookup(entry.sourceCode.Filename); found {
+			m.Files = append(m.Files, file)
+			recorded[entry.sourceCode.Filename] = true
+		}
+	}
+	for _, stale := range p.stale {
+		if stale.tracked {
+			m.Files = append(m.Files, stale.manifestFile)
+		}
+	}
+	return m
+}
+
+// prune deletes the stale files marked for pruning, and any directories left empty by that up to
+// outputDirectory. Each removal, and each stale file kept because it was modified, is reported on out.
+func (p plan) prune(outputDirectory string, out io.Writer) error {
+	root := filepath.Clean(outputDirectory)
+	for _, stale := range p.stale {
+		if stale.action != actionPrune {
+			if !stale.tracked {
+				fmt.Fprintf(out, "Keeping file: %s (%s)\n", stale.Path, stale.note)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "Removing file: %s\n", stale.Path)
+		if err := os.Remove(stale.destination); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune %s: %w", stale.Path, err)
+		}
+		for dir := filepath.Dir(stale.destination); below(root, dir); dir = filepath.Dir(dir) {
+			// Remove fails on directories that still have entries, which ends the climb
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// below reports whether dir is strictly inside root, comparing absolute paths so that a relative
+// output directory such as "." works.
+func below(root, dir string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
+
+// suffixed inserts -n before the extension of filename, e.g. main.go becomes main-1.go.
+func suffixed(filename string, n int) string {
+	ext := path.Ext(filename)
+	if ext == filename || strings.HasSuffix(filename, "/"+ext) {
+		// Dotfiles such as .env have no extension to preserve
+		ext = ""
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), n, ext)
+}
+
+// conflictErrors returns an error describing the collisions and unsafe filenames in the plan,
+// each with the source location of its block, or nil if there are none.
+func (p plan) conflictErrors() error {
+	var messages []string
+	for _, entry := range p.entries {
+		switch entry.action {
+		case actionCollision:
+			messages = append(messages, fmt.Sprintf("%s: %s %s (see --on-conflict)", entry.block.Position, entry.sourceCode.Filename, entry.note))
+		case actionUnsafe:
+			messages = append(messages, fmt.Sprintf("%s: %s", entry.block.Position, entry.note))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d conflicting files, nothing was written:\n  %s", len(messages), strings.Join(messages, "\n  "))
+}
+
+// conflicts returns the number of entries that cannot be written as planned.
+func (p plan) conflicts() int {
+	n := 0
+	for _, entry := range p.entries {
+		if entry.action == actionCollision || entry.action == actionUnsafe {
+			n++
+		}
+	}
+	return n
+}
+
+// counts returns the number of entries per action.
+func (p plan) counts() map[string]int {
+	counts := make(map[string]int)
+	for _, entry := range p.entries {
+		counts[entry.action]++
+	}
+	for _, stale := range p.stale {
+		counts[stale.action]++
+	}
+	return counts
+}
+
+// writeTable prints the plan as a table followed by a summary line.
+func (p plan) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tFILE\tLANGUAGE\tSOURCE")
+	for _, entry := range p.entries {
+		source := entry.block.Position.String()
+		if entry.note != "" {
+			source += " (" + entry.note + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.action, entry.sourceCode.Filename, entry.block.Language, source)
+	}
+	for _, stale := range p.stale {
+		fmt.Fprintf(tw, "%s\t%s\t\t%s (%s)\n", stale.action, stale.Path, stale.source(), stale.note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	counts := p.counts()
+	if _, err := fmt.Fprintf(w, "\n%d files: %d to create, %d to overwrite, %d to skip, %d conflicts\n",
+		len(p.entries)-counts[actionAppend]-counts[actionReplace], counts[actionCreate], counts[actionOverwrite], counts[actionSkip], p.conflicts()); err != nil {
+		return err
+	}
+	if counts[actionAppend] > 0 {
+		if _, err := fmt.Fprintf(w, "%d blocks appended to earlier files\n", counts[actionAppend]); err != nil {
+			return err
+		}
+	}
+	if counts[actionReplace] > 0 {
+		if _, err := fmt.Fprintf(w, "%d blocks replaced by later blocks\n", counts[actionReplace]); err != nil {
+			return err
+		}
+	}
+	if len(p.stale) > 0 {
+		_, err := fmt.Fprintf(w, "%d stale files: %d to prune, %d to keep\n",
+			len(p.stale), counts[actionPrune], counts[actionStale])
+		return err
+	}
+	return nil
+}
+
+// planRecord is the JSON representation of a plan entry.
+type planRecord struct {
+	Action      string `json:"action"`
+	Filename    string `json:"filename"`
+	Destination string `json:"destination"`
+	Language    string `json:"language"`
+	Source      string `json:"source"`
+	Note        string `json:"note,omitempty"`
+}
+
+// writeJSON prints the plan as a JSON array or, with ndjson, one object per line.
+func (p plan) writeJSON(w io.Writer, ndjson bool) error {
+	records := make([]planRecord, 0, len(p.entries))
+	for _, entry := range p.entries {
+		records = append(records, planRecord{
+			Action:      entry.action,
+			Filename:    entry.sourceCode.Filename,
+			Destination: entry.destination,
+			Language:    entry.block.Language,
+			Source:      entry.block.Position.String(),
+			Note:        entry.note,
+		})
+	}
+	for _, stale := range p.stale {
+		records = append(records, planRecord{
+			Action:      stale.action,
+			Filename:    stale.Path,
+			Destination: stale.destination,
+			Source:      stale.source(),
+			Note:        stale.note,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	if ndjson {
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
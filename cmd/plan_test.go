@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	outputDirectory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDirectory, "existing.py"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	markdown := "```go filename=\"main.go\"\npackage a\n```\n\n" +
+		"```go filename=\"main.go\"\npackage b\n```\n\n" +
+		"```python filename=\"existing.py\"\nprint(1)\n```\n\n" +
+		"```bash\necho hi\n```\n"
+	plan := newPlan(extractMarkdown(t, markdown), outputDirectory, planOptions{onConflict: conflictError})
+
+	expected := []struct {
+		action   string
+		filename string
+	}{
+		{actionCreate, "main.go"},
+		{actionCollision, "main.go"},
+		{actionOverwrite, "existing.py"},
+		{actionCreate, "sourcecode.sh"},
+	}
+	if len(plan.entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(plan.entries))
+	}
+	for i, entry := range plan.entries {
+		if entry.action != expected[i].action || entry.sourceCode.Filename != expected[i].filename {
+			t.Errorf("Entry %d: Expected %s %s, got %s %s", i, expected[i].action, expected[i].filename, entry.action, entry.sourceCode.Filename)
+		}
+		if entry.destination != filepath.Join(outputDirectory, expected[i].filename) {
+			t.Errorf("Entry %d: Unexpected destination %s", i, entry.destination)
+		}
+	}
+	if !strings.HasSuffix(plan.entries[1].note, ":1:1") {
+		t.Errorf("Expected the collision to point at the first block, got %q", plan.entries[1].note)
+	}
+	if plan.conflicts() != 1 {
+		t.Errorf("Expected 1 conflict, got %d", plan.conflicts())
+	}
+
+	// Planning never writes anything
+	entries, err := os.ReadDir(outputDirectory)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected the output directory to be untouched, found %d entries", len(entries))
+	}
+
+	var table bytes.Buffer
+	if err := plan.writeTable(&table); err != nil {
+		t.Fatalf("Failed to write table: %v", err)
+	}
+	if !strings.Contains(table.String(), "4 files: 2 to create, 1 to overwrite, 0 to skip, 1 conflicts") {
+		t.Errorf("Unexpected table:\n%s", table.String())
+	}
+
+	var buf bytes.Buffer
+	if err := plan.writeJSON(&buf, false); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var records []planRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(records) != 4 || records[1].Action != actionCollision || records[1].Note == "" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestPlanConflictPolicies(t *testing.T) {
+	markdown := "```go filename=\"main.go\"\npackage a\n```\n\n" +
+		"```go filename=\"main.go\"\npackage b\n```\n\n" +
+		"```go filename=\"main.go\"\npackage c\n```\n\n" +
+		"```bash filename=\".env\"\nA=1\n```\n\n" +
+		"```bash filename=\".env\"\nA=2\n```\n"
+	extractions := extractMarkdown(t, markdown)
+
+	tests := []struct {
+		policy    string
+		actions   []string
+		filenames []string
+	}{
+		{
+			conflictError,
+			[]string{actionCreate, actionCollision, actionCollision, actionCreate, actionCollision},
+			[]string{"main.go", "main.go", "main.go", ".env", ".env"},
+		},
+		{
+			conflictOverwrite,
+			[]string{actionReplace, actionReplace, actionCreate, actionReplace, actionCreate},
+			[]string{"main.go", "main.go", "main.go", ".env", ".env"},
+		},
+		{
+			conflictSuffix,
+			[]string{actionCreate, actionCreate, actionCreate, actionCreate, actionCreate},
+			[]string{"main.go", "main-1.go", "main-2.go", ".env", ".env-1"},
+		},
+		{
+			conflictSkip,
+			[]string{actionCreate, actionSkip, actionSkip, actionCreate, actionSkip},
+			[]string{"main.go", "main.go", "main.go", ".env", ".env"},
+		},
+		{
+			conflictConcat,
+			[]string{actionCreate, actionAppend, actionAppend, actionCreate, actionAppend},
+			[]string{"main.go", "main.go", "main.go", ".env", ".env"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			plan := newPlan(extractions, t.TempDir(), planOptions{onConflict: tt.policy})
+			for i, entry := range plan.entries {
+				if entry.action != tt.actions[i] || entry.sourceCode.Filename != tt.filenames[i] {
+					t.Errorf("Entry %d: Expected %s %s, got %s %s", i, tt.actions[i], tt.filenames[i], entry.action, entry.sourceCode.Filename)
+				}
+			}
+			if (tt.policy == conflictError) != (plan.conflictErrors() != nil) {
+				t.Errorf("Unexpected conflict errors: %v", plan.conflictErrors())
+			}
+		})
+	}
+
+	// Replaced blocks are neither files nor skips
+	var table bytes.Buffer
+	if err := newPlan(extractions, t.TempDir(), planOptions{onConflict: conflictOverwrite}).writeTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if summary := table.String(); !strings.Contains(summary, "2 files: 2 to create, 0 to overwrite, 0 to skip") ||
+		!strings.Contains(summary, "3 blocks replaced by later blocks") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+
+	if err := validateConflictPolicy("rename"); err == nil {
+		t.Error("Expected an unknown conflict policy to be rejected")
+	}
+}
+
+func TestPlanConcat(t *testing.T) {
+	markdown := "First the imports:\n\n```go filename=\"main.go\"\npackage main\n\nimport \"fmt\"\n```\n\n" +
+		"```bash\ngo mod init example\n```\n\n" +
+		"Now the handler:\n\n```go filename=\"main.go\"\nfunc main() { fmt.Println() }\n```\n"
+	extractions := extractMarkdown(t, markdown)
+
+	tests := []struct {
+		separator string
+		expected  string
+	}{
+		{"", "package main\n\nimport \"fmt\"\nfunc main() { fmt.Println() }\n"},
+		{"\n", "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n"},
+	}
+	for _, tt := range tests {
+		plan := newPlan(extractions, t.TempDir(), planOptions{onConflict: conflictConcat, separator: tt.separator})
+		if len(plan.entries) != 3 || plan.entries[2].action != actionAppend {
+			t.Fatalf("Expected the third block to be appended, got %+v", plan.entries)
+		}
+		if content := plan.entries[0].sourceCode.Content; content != tt.expected {
+			t.Errorf("Separator %q: Expected:\n%s\ngot:\n%s", tt.separator, tt.expected, content)
+		}
+		if plan.entries[1].sourceCode.Content != "go mod init example\n" {
+			t.Errorf("Expected the unrelated block to be untouched, got %q", plan.entries[1].sourceCode.Content)
+		}
+	}
+
+	// Escapes in the configured separator are interpreted
+	setConfig(t, map[string]any{"concat-separator": `\n// ---\n`})
+	if separator, err := concatSeparator(); err != nil || separator != "\n// ---\n" {
+		t.Errorf("Unexpected separator %q, %v", separator, err)
+	}
+}
+
+func TestPlanNoClobber(t *testing.T) {
+	outputDirectory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDirectory, "main.go"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	markdown := "```go filename=\"main.go\"\npackage a\n```\n\n```go filename=\"other.go\"\npackage b\n```\n"
+	plan := newPlan(extractMarkdown(t, markdown), outputDirectory, planOptions{onConflict: conflictError, noClobber: true})
+
+	if plan.entries[0].action != actionSkip || plan.entries[0].note != "file exists" {
+		t.Errorf("Expected the existing file to be skipped, got %s (%s)", plan.entries[0].action, plan.entries[0].note)
+	}
+	if plan.entries[1].action != actionCreate {
+		t.Errorf("Expected the new file to be created, got %s", plan.entries[1].action)
+	}
+}
+
+func TestPlanUnsafePaths(t *testing.T) {
+	outputDirectory := t.TempDir()
+	markdown := "```go filename=\"../escape.go\"\npackage a\n```\n\n" +
+		"```go filename=\"safe/main.go\"\npackage b\n```\n"
+	plan := newPlan(extractMarkdown(t, markdown), outputDirectory, planOptions{onConflict: conflictError})
+
+	if plan.entries[0].action != actionUnsafe {
+		t.Errorf("Expected the escaping filename to be unsafe, got %s", plan.entries[0].action)
+	}
+	if plan.entries[1].action != actionCreate {
+		t.Errorf("Expected the safe filename to be created, got %s", plan.entries[1].action)
+	}
+	if plan.conflicts() != 1 {
+		t.Errorf("Expected 1 conflict, got %d", plan.conflicts())
+	}
+
+	err := plan.conflictErrors()
+	if err == nil {
+		t.Fatal("Expected an error for the unsafe filename")
+	}
+	// The error points at the offending block
+	if !strings.Contains(err.Error(), "doc.md:1:1") || !strings.Contains(err.Error(), "escapes the output directory") {
+		t.Errorf("Expected the error to report the block location, got %v", err)
+	}
+}
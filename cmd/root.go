@@ -23,15 +23,11 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/spandigitial/codeblocks/model"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/ast"
-	"github.com/yuin/goldmark/text"
 	"io"
 	"log"
 	"os"
-	"strings"
 
+	"github.com/spandigitial/codeblocks/model"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -40,96 +36,134 @@ var cfgFile string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "codeblocks",
+	Use:   "codeblocks [paths...]",
 	Short: "Extract fenced code blocks from markdown",
-	Long:  `Extracts fenced code blocks from markdown`,
+	Long: `Extracts fenced code blocks from markdown.
+
+Paths may be Markdown files, glob patterns (including **) or directories, which are
+walked recursively for .md, .markdown and .mdx files. With more than one document,
+output is written to a subdirectory per document mirroring the input tree.
+Without paths, markdown is read from --input or stdin.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		input := viper.GetString("input")
-		var source []byte
-		var err error
-		if input == "" {
-			source, err = io.ReadAll(os.Stdin)
+		format := viper.GetString("format")
+		switch format {
+		case "", "files", "json", "ndjson":
+		default:
+			return fmt.Errorf("unknown format %q, expected files, json or ndjson", format)
+		}
+
+		cmd.SilenceUsage = true // Errors from here on are not about how the command was invoked
+
+		_, err := run(args, cmd.OutOrStdout(), os.Stderr)
+		return err
+	},
+}
+
+// runResult is what a run planned and what it did to the output directory.
+type runResult struct {
+	plan      plan
+	written   int
+	unchanged int
+	skipped   int
+}
+
+// run extracts the blocks of args with the configured flags and carries out the plan: it writes
+// the files, prunes stale ones and updates the manifest, or with --dry-run or --format json only
+// reports on stdout. Progress goes to stderr.
+func run(args []string, stdout, stderr io.Writer) (runResult, error) {
+	var result runResult
+	format := viper.GetString("format")
+	extractions, err := extractAll(args)
+	if err != nil {
+		return result, err
+	}
+
+	outputDirectory, err := outputDirectory()
+	if err != nil {
+		return result, err
+	}
+
+	onConflict := viper.GetString("on-conflict")
+	if err := validateConflictPolicy(onConflict); err != nil {
+		return result, err
+	}
+	separator, err := concatSeparator()
+	if err != nil {
+		return result, err
+	}
+	plan := newPlan(extractions, outputDirectory, planOptions{
+		onConflict: onConflict,
+		separator:  separator,
+		noClobber:  viper.GetBool("no-clobber"),
+	})
+	result.plan = plan
+
+	// Listing blocks reports the filenames the plan resolved, e.g. with --on-conflict suffix
+	dryRun := viper.GetBool("dry-run")
+	if !dryRun && (format == "json" || format == "ndjson") {
+		return result, writeJSON(stdout, plan.extractions(), format == "ndjson")
+	}
+
+	previous, err := readManifest(outputDirectory)
+	if err != nil {
+		return result, err
+	}
+	plan.addStale(previous, outputDirectory, viper.GetBool("prune"))
+	result.plan = plan
+
+	if dryRun {
+		if format == "json" || format == "ndjson" {
+			err = plan.writeJSON(stdout, format == "ndjson")
 		} else {
-			source, err = os.ReadFile(input)
+			err = plan.writeTable(stdout)
 		}
 		if err != nil {
-			return err
+			return result, err
 		}
-
-		extension := viper.GetString("extension")
-		if extension == "" {
-			extension = "txt"
-		}
-		filenamePrefix := viper.GetString("filename-prefix")
-		if filenamePrefix == "" {
-			filenamePrefix = "sourcecode"
+		if conflicts := plan.conflicts(); conflicts > 0 {
+			return result, fmt.Errorf("plan has %d conflicts", conflicts)
 		}
+		return result, nil
+	}
 
-		outputDirectory := viper.GetString("output-directory")
-		if outputDirectory == "" {
-			outputDirectory, err = os.Getwd()
+	if err := plan.conflictErrors(); err != nil {
+		return result, err
+	}
+	writer, err := newWriter(outputDirectory)
+	if err != nil {
+		return result, err
+	}
+	writer.Output = stderr
+	for _, entry := range plan.entries {
+		switch entry.action {
+		case actionCreate, actionOverwrite:
+			changed, err := writer.Write(entry.sourceCode)
 			if err != nil {
-				return err
+				return result, fmt.Errorf("%s: failed to save %s: %w", entry.block.Position, entry.sourceCode.Filename, err)
 			}
-		}
-
-		node := goldmark.DefaultParser().Parse(text.NewReader(source))
-		var codeBlocks []model.FencedCodeBlock
-		ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
-			if node.Kind() == ast.KindFencedCodeBlock {
-				var language string
-				var content string
-
-				fcb := node.(*ast.FencedCodeBlock)
-				if !entering && fcb.Info != nil {
-					segment := fcb.Info.Segment
-					language = string(source[segment.Start:segment.Stop])
-					var sb strings.Builder
-					lines := fcb.BaseBlock.Lines()
-					l := lines.Len()
-					for i := 0; i < l; i++ {
-						line := lines.At(i)
-						sb.Write(line.Value(source))
-					}
-					content = sb.String()
-					if language != "" && content != "" {
-						codeBlocks = append(codeBlocks, model.FencedCodeBlock{
-							Language: language,
-							Content:  content,
-						})
-					}
-				}
-
+			if changed {
+				result.written++
+			} else {
+				result.unchanged++
 			}
+		case actionSkip:
+			fmt.Fprintf(stderr, "Skipping file: %s (%s)\n", entry.sourceCode.Filename, entry.note)
+			result.skipped++
+		}
+	}
+	if err := plan.prune(outputDirectory, stderr); err != nil {
+		return result, err
+	}
 
-			return ast.WalkContinue, nil
-		})
-
-		l := len(codeBlocks)
-		userSpecifiedExtension := viper.GetString("extension") != "" // Check if user provided --extension
-
-		for i, codeBlock := range codeBlocks {
-			sourceCode := codeBlock.ToSourceCode(func(block model.FencedCodeBlock) string {
-				// Determine extension: user override > language detection > default fallback
-				fileExtension := extension // Default
-				if !userSpecifiedExtension {
-					// Auto-detect extension from language (handles empty strings)
-					fileExtension = model.LanguageToExtension(block.Language)
-				}
-
-				if l == 1 {
-					return fmt.Sprintf("%s.%s", filenamePrefix, fileExtension)
-				} else {
-					return fmt.Sprintf("%s-%d.%s", filenamePrefix, i, fileExtension)
-				}
-			})
-			if err := sourceCode.Save(outputDirectory); err != nil {
-				return fmt.Errorf("failed to save %s: %w", sourceCode.Filename, err)
-			}
+	if m := plan.manifest(previous); len(m.Files) > 0 || len(previous.Files) > 0 {
+		if err := m.write(outputDirectory); err != nil {
+			return result, err
 		}
+	}
 
-		return nil
-	},
+	fmt.Fprintf(stderr, "%d written, %d unchanged, %d skipped\n", result.written, result.unchanged, result.skipped)
+	return result, nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -149,25 +183,116 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.codeblocks.yaml)")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Also report created directories and unchanged files")
+	if err := viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose")); err != nil {
+		log.Fatal("Unable to bind flag verbose", err)
+	}
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().StringP("input", "i", "", "Input (defaults to stdin)")
-	if err := viper.BindPFlag("input", rootCmd.Flags().Lookup("input")); err != nil {
+	// Flags that decide which blocks are extracted and where they go are persistent, so that
+	// subcommands such as check see exactly what a normal run would write.
+	rootCmd.PersistentFlags().StringP("input", "i", "", "Input (defaults to stdin)")
+	if err := viper.BindPFlag("input", rootCmd.PersistentFlags().Lookup("input")); err != nil {
 		log.Fatal("Unable to bind flag input", err)
 	}
-	rootCmd.Flags().StringP("extension", "e", "", "Extension (defaults to txt)")
-	if err := viper.BindPFlag("extension", rootCmd.Flags().Lookup("extension")); err != nil {
+	rootCmd.PersistentFlags().StringP("extension", "e", "", "Extension (defaults to txt)")
+	if err := viper.BindPFlag("extension", rootCmd.PersistentFlags().Lookup("extension")); err != nil {
 		log.Fatal("Unable to bind flag extension", err)
 	}
-	rootCmd.Flags().StringP("filename-prefix", "f", "", "Filename prefix (defaults to sourcecode)")
-	if err := viper.BindPFlag("filename-prefix", rootCmd.Flags().Lookup("filename-prefix")); err != nil {
+	rootCmd.PersistentFlags().StringP("filename-prefix", "f", "", "Filename prefix (defaults to sourcecode)")
+	if err := viper.BindPFlag("filename-prefix", rootCmd.PersistentFlags().Lookup("filename-prefix")); err != nil {
 		log.Fatal("Unable to bind filename-prefix", err)
 	}
-	rootCmd.Flags().StringP("output-directory", "o", "", "Output directory (defaults to current working directory)")
-	if err := viper.BindPFlag("output-directory", rootCmd.Flags().Lookup("output-directory")); err != nil {
+	rootCmd.PersistentFlags().StringP("output-directory", "o", "", "Output directory (defaults to current working directory)")
+	if err := viper.BindPFlag("output-directory", rootCmd.PersistentFlags().Lookup("output-directory")); err != nil {
 		log.Fatal("Unable to bind flag output-directory", err)
 	}
+	rootCmd.PersistentFlags().String("name-template", "", "Go text/template for output filenames, e.g. {{.InputBase}}-{{.Heading}}-{{.LanguageIndex}}.{{.Ext}}")
+	if err := viper.BindPFlag("name-template", rootCmd.PersistentFlags().Lookup("name-template")); err != nil {
+		log.Fatal("Unable to bind flag name-template", err)
+	}
+	rootCmd.PersistentFlags().StringSlice("lang", nil, "Only extract blocks in these languages, matched through aliases (repeatable)")
+	if err := viper.BindPFlag("lang", rootCmd.PersistentFlags().Lookup("lang")); err != nil {
+		log.Fatal("Unable to bind flag lang", err)
+	}
+	rootCmd.PersistentFlags().StringSlice("exclude-lang", nil, "Skip blocks in these languages, matched through aliases (repeatable)")
+	if err := viper.BindPFlag("exclude-lang", rootCmd.PersistentFlags().Lookup("exclude-lang")); err != nil {
+		log.Fatal("Unable to bind flag exclude-lang", err)
+	}
+	rootCmd.PersistentFlags().StringArray("section", nil, "Only extract blocks under this heading: text, slug or path such as \"Usage > Examples\"; /regex/ components allowed (repeatable)")
+	if err := viper.BindPFlag("section", rootCmd.PersistentFlags().Lookup("section")); err != nil {
+		log.Fatal("Unable to bind flag section", err)
+	}
+	rootCmd.PersistentFlags().Bool("include-untagged", false, "Also extract fenced blocks without a language")
+	if err := viper.BindPFlag("include-untagged", rootCmd.PersistentFlags().Lookup("include-untagged")); err != nil {
+		log.Fatal("Unable to bind flag include-untagged", err)
+	}
+	rootCmd.PersistentFlags().String("untagged-language", "", "Language assumed for untagged fenced blocks (defaults to the shebang interpreter, else txt)")
+	if err := viper.BindPFlag("untagged-language", rootCmd.PersistentFlags().Lookup("untagged-language")); err != nil {
+		log.Fatal("Unable to bind flag untagged-language", err)
+	}
+	rootCmd.PersistentFlags().Bool("include-indented", false, "Also extract indented (non-fenced) code blocks")
+	if err := viper.BindPFlag("include-indented", rootCmd.PersistentFlags().Lookup("include-indented")); err != nil {
+		log.Fatal("Unable to bind flag include-indented", err)
+	}
+	rootCmd.PersistentFlags().String("indented-language", "", "Language assumed for indented blocks (defaults to the shebang interpreter, else txt)")
+	if err := viper.BindPFlag("indented-language", rootCmd.PersistentFlags().Lookup("indented-language")); err != nil {
+		log.Fatal("Unable to bind flag indented-language", err)
+	}
+	rootCmd.PersistentFlags().Bool("detect-language", false, "Guess the language of untagged blocks and blocks with an unknown language from their content")
+	if err := viper.BindPFlag("detect-language", rootCmd.PersistentFlags().Lookup("detect-language")); err != nil {
+		log.Fatal("Unable to bind flag detect-language", err)
+	}
+	rootCmd.PersistentFlags().Bool("include-empty", false, "Also extract blocks without content")
+	if err := viper.BindPFlag("include-empty", rootCmd.PersistentFlags().Lookup("include-empty")); err != nil {
+		log.Fatal("Unable to bind flag include-empty", err)
+	}
+	rootCmd.PersistentFlags().String("on-conflict", conflictConcat, "What to do when blocks resolve to the same file: concat (in document order), error, overwrite (last wins), suffix (main-1.go) or skip (first wins)")
+	if err := viper.BindPFlag("on-conflict", rootCmd.PersistentFlags().Lookup("on-conflict")); err != nil {
+		log.Fatal("Unable to bind flag on-conflict", err)
+	}
+	rootCmd.PersistentFlags().String("concat-separator", "", "Text inserted between concatenated blocks; escapes such as \\n are interpreted")
+	if err := viper.BindPFlag("concat-separator", rootCmd.PersistentFlags().Lookup("concat-separator")); err != nil {
+		log.Fatal("Unable to bind flag concat-separator", err)
+	}
+	rootCmd.PersistentFlags().StringSlice("include", nil, "Only process files matching these patterns when walking directories or globs (defaults to *.md, *.markdown, *.mdx)")
+	if err := viper.BindPFlag("include", rootCmd.PersistentFlags().Lookup("include")); err != nil {
+		log.Fatal("Unable to bind flag include", err)
+	}
+	rootCmd.PersistentFlags().StringSlice("exclude", nil, "Skip files matching these patterns when walking directories or globs")
+	if err := viper.BindPFlag("exclude", rootCmd.PersistentFlags().Lookup("exclude")); err != nil {
+		log.Fatal("Unable to bind flag exclude", err)
+	}
+	rootCmd.PersistentFlags().Bool("gitignore", true, "Skip files ignored by .gitignore files in walked directories and above them, up to the git repository root")
+	if err := viper.BindPFlag("gitignore", rootCmd.PersistentFlags().Lookup("gitignore")); err != nil {
+		log.Fatal("Unable to bind flag gitignore", err)
+	}
+
+	// Cobra also supports local flags, which will only run
+	// when this action is called directly.
+	rootCmd.Flags().String("format", "files", "Output format: files writes the blocks to disk, json or ndjson lists them on stdout")
+	if err := viper.BindPFlag("format", rootCmd.Flags().Lookup("format")); err != nil {
+		log.Fatal("Unable to bind flag format", err)
+	}
+	rootCmd.Flags().String("dir-mode", "0755", "Permissions of created output directories (octal)")
+	if err := viper.BindPFlag("dir-mode", rootCmd.Flags().Lookup("dir-mode")); err != nil {
+		log.Fatal("Unable to bind flag dir-mode", err)
+	}
+	rootCmd.Flags().Bool("prune", false, "Delete files generated by a previous run that this run no longer produces")
+	if err := viper.BindPFlag("prune", rootCmd.Flags().Lookup("prune")); err != nil {
+		log.Fatal("Unable to bind flag prune", err)
+	}
+	rootCmd.Flags().String("file-mode", "0644", "Permissions of written files (octal); files starting with #! also get the execute bit")
+	if err := viper.BindPFlag("file-mode", rootCmd.Flags().Lookup("file-mode")); err != nil {
+		log.Fatal("Unable to bind flag file-mode", err)
+	}
+	rootCmd.Flags().Bool("dry-run", false, "Print what would be written, without writing anything; fails if the plan has conflicts")
+	if err := viper.BindPFlag("dry-run", rootCmd.Flags().Lookup("dry-run")); err != nil {
+		log.Fatal("Unable to bind flag dry-run", err)
+	}
+	rootCmd.Flags().Bool("no-clobber", false, "Never replace files that already exist on disk")
+	if err := viper.BindPFlag("no-clobber", rootCmd.Flags().Lookup("no-clobber")); err != nil {
+		log.Fatal("Unable to bind flag no-clobber", err)
+	}
 
 }
 
@@ -193,4 +318,5 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+	cobra.CheckErr(registerLanguages(model.DefaultLanguages, viper.GetStringMapString("languages")))
 }
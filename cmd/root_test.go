@@ -7,11 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/spandigitial/codeblocks/extract"
 	"github.com/spandigitial/codeblocks/model"
 	"github.com/spf13/viper"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/ast"
-	"github.com/yuin/goldmark/text"
 )
 
 // Test helper functions
@@ -48,38 +46,10 @@ func fileExists(path string) bool {
 
 func extractCodeBlocks(t *testing.T, markdown string) []model.FencedCodeBlock {
 	t.Helper()
-	source := []byte(markdown)
-	node := goldmark.DefaultParser().Parse(text.NewReader(source))
-	var codeBlocks []model.FencedCodeBlock
-
-	ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
-		if node.Kind() == ast.KindFencedCodeBlock {
-			var language string
-			var content string
-
-			fcb := node.(*ast.FencedCodeBlock)
-			if !entering && fcb.Info != nil {
-				segment := fcb.Info.Segment
-				language = string(source[segment.Start:segment.Stop])
-				var sb strings.Builder
-				lines := fcb.BaseBlock.Lines()
-				l := lines.Len()
-				for i := 0; i < l; i++ {
-					line := lines.At(i)
-					sb.Write(line.Value(source))
-				}
-				content = sb.String()
-				if language != "" && content != "" {
-					codeBlocks = append(codeBlocks, model.FencedCodeBlock{
-						Language: language,
-						Content:  content,
-					})
-				}
-			}
-		}
-		return ast.WalkContinue, nil
-	})
-
+	codeBlocks, err := extract.New().Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract code blocks: %v", err)
+	}
 	return codeBlocks
 }
 
@@ -660,6 +630,60 @@ func TestCaseInsensitiveLanguages(t *testing.T) {
 	}
 }
 
+func TestInfoStringAttributes(t *testing.T) {
+	markdown := "```go title=\"main.go\" {3-5}\npackage main\n```\n"
+
+	codeBlocks := extractCodeBlocks(t, markdown)
+	if len(codeBlocks) != 1 {
+		t.Fatalf("Expected 1 code block, got %d", len(codeBlocks))
+	}
+
+	codeBlock := codeBlocks[0]
+	if codeBlock.Language != "go" {
+		t.Errorf("Expected language go, got %q", codeBlock.Language)
+	}
+	if ext := model.LanguageToExtension(codeBlock.Language); ext != "go" {
+		t.Errorf("Expected extension go, got %s", ext)
+	}
+	if title, _ := codeBlock.Attributes.Get("title"); title != "main.go" {
+		t.Errorf("Expected title attribute main.go, got %q", title)
+	}
+}
+
+func TestFilenameAttribute(t *testing.T) {
+	testDir := setupTestDir(t)
+	defer cleanupTestDir(t, testDir)
+
+	markdown := "```go filename=\"cmd/server/main.go\"\npackage main\n```\n\n" +
+		"```go\npackage other\n```\n"
+
+	codeBlocks := extractCodeBlocks(t, markdown)
+	if len(codeBlocks) != 2 {
+		t.Fatalf("Expected 2 code blocks, got %d", len(codeBlocks))
+	}
+
+	if filename := codeBlocks[0].Attributes.Filename(); filename != "cmd/server/main.go" {
+		t.Fatalf("Expected filename attribute cmd/server/main.go, got %q", filename)
+	}
+	if filename := codeBlocks[1].Attributes.Filename(); filename != "" {
+		t.Fatalf("Expected no filename attribute, got %q", filename)
+	}
+
+	sourceCode := codeBlocks[0].ToSourceCode(func(block model.FencedCodeBlock) string {
+		return block.Attributes.Filename()
+	})
+
+	// Nested directories are created as needed
+	if err := sourceCode.Save(testDir); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	expectedPath := filepath.Join(testDir, "cmd", "server", "main.go")
+	if content := readFile(t, expectedPath); content != "package main\n" {
+		t.Errorf("Unexpected content in %s: %q", expectedPath, content)
+	}
+}
+
 // Reset viper for isolated tests
 func TestMain(m *testing.M) {
 	// Run tests
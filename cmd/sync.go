@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncMarker matches an HTML comment binding the next code block to a file, e.g.
+// <!-- codeblocks file="src/main.go" region=setup -->.
+var syncMarker = regexp.MustCompile(`^<!--\s*codeblocks\s+(.*?)\s*-->$`)
+
+// syncCmd copies the content of source files back into the code blocks bound to them.
+var syncCmd = &cobra.Command{
+	Use:     "sync [paths...]",
+	Aliases: []string{"embed"},
+	Short:   "Update code blocks in Markdown from the files they are bound to",
+	Long: `Replaces the content of code blocks with the current content of the files they
+are bound to, the reverse of a normal run. A block is bound by a filename=
+attribute, resolved like a normal run would write it under the output directory,
+or by an HTML comment on the line before it, resolved relative to the Markdown file:
+
+    <!-- codeblocks file="../src/main.go" lines=10-25 -->
+
+A lines= or region= attribute on the fence or the comment embeds only part of the
+file: a 1-based line range, or the lines between "#region name" and "#endregion"
+comments. Everything outside the replaced block bodies, including fences and
+indentation, is preserved byte for byte.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && viper.GetString("input") == "" {
+			return errors.New("sync rewrites Markdown files, pass them as arguments")
+		}
+		extractions, err := extractAll(args)
+		if err != nil {
+			return err
+		}
+		outputDirectory, err := outputDirectory()
+		if err != nil {
+			return err
+		}
+
+		// Extractions are in document order; group them by document
+		var documents []string
+		blocks := make(map[string][]extraction)
+		for _, e := range extractions {
+			document := e.block.Position.Path
+			if document == "" {
+				return errors.New("sync rewrites Markdown files and cannot read standard input")
+			}
+			if _, found := blocks[document]; !found {
+				documents = append(documents, document)
+			}
+			blocks[document] = append(blocks[document], e)
+		}
+
+		for _, document := range documents {
+			source, err := os.ReadFile(document)
+			if err != nil {
+				return err
+			}
+			updated, count, err := syncDocument(source, blocks[document], outputDirectory, os.Stderr)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				continue
+			}
+			if dryRun {
+				if err := unifiedDiff(cmd.OutOrStdout(), "a/"+filepath.ToSlash(document), "b/"+filepath.ToSlash(document), string(source), string(updated)); err != nil {
+					return err
+				}
+				continue
+			}
+			info, err := os.Stat(document)
+			if err != nil {
+				return err
+			}
+			writer := model.Writer{Root: filepath.Dir(document), Output: io.Discard}
+			if _, err := writer.Write(model.SourceCode{Filename: filepath.Base(document), Content: string(updated), Mode: info.Mode().Perm()}); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Updated %s (%d blocks)\n", document, count)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().Bool("dry-run", false, "Print the changes to the Markdown as a unified diff without writing them")
+}
+
+// syncSource is the file, or part of a file, that a block is kept in sync with.
+type syncSource struct {
+	path   string // on disk
+	lines  string // 1-based line range such as 3-10, empty for the whole file
+	region string // name of a #region, empty for the whole file
+	// byFilename is set for blocks bound by their filename attribute rather than a comment marker
+	byFilename bool
+	// shared is set when other blocks of the document are bound to the same file by their filename
+	shared bool
+}
+
+// syncDocument replaces the content of every bound block in source with the content of its file and
+// returns the updated document with the number of blocks that changed. Blocks whose file does not
+// exist, blocks assembled from chunks and blocks sharing their file with others are reported on
+// warn and left alone.
+func syncDocument(source []byte, blocks []extraction, outputDirectory string, warn io.Writer) ([]byte, int, error) {
+	// Blocks concatenated into one file cannot each take the whole file back
+	shared := make(map[string]int)
+	for _, e := range blocks {
+		if e.block.Attributes.Filename() != "" {
+			shared[e.sourceCode.Filename]++
+		}
+	}
+
+	var sb strings.Builder
+	written, count := 0, 0
+	for _, e := range blocks {
+		binding, bound, err := syncBinding(source, e, outputDirectory)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", e.block.Position, err)
+		}
+		binding.shared = binding.byFilename && shared[e.sourceCode.Filename] > 1
+		if !bound || e.block.Indented {
+			continue
+		}
+		if e.tangled {
+			fmt.Fprintf(warn, "Skipping block at %s: its content is assembled from chunks\n", e.block.Position)
+			continue
+		}
+		if binding.shared {
+			fmt.Fprintf(warn, "Skipping block at %s: %s is concatenated from several blocks\n", e.block.Position, e.sourceCode.Filename)
+			continue
+		}
+
+		content, err := binding.load()
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(warn, "Skipping block at %s: %s does not exist\n", e.block.Position, binding.path)
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", e.block.Position, err)
+		}
+
+		start, end, replacement, err := replaceBody(source, e.block, content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", e.block.Position, err)
+		}
+		if string(source[start:end]) == replacement {
+			continue
+		}
+		sb.Write(source[written:start])
+		sb.WriteString(replacement)
+		written = end
+		count++
+	}
+	sb.Write(source[written:])
+	return []byte(sb.String()), count, nil
+}
+
+// syncBinding returns the file a block is bound to by an HTML comment marker on a line before it
+// or, failing that, by its filename attribute, and false if it is not bound.
+// Lines and region attributes on the marker win over those on the fence.
+func syncBinding(source []byte, e extraction, outputDirectory string) (syncSource, bool, error) {
+	lines, _ := e.block.Attributes.Get("lines")
+	region, _ := e.block.Attributes.Get("region")
+
+	if marker, found := markerBefore(source, e.block.Position.StartOffset); found {
+		_, attributes := model.ParseInfo("codeblocks " + marker)
+		file, _ := attributes.Get("file")
+		if file == "" {
+			return syncSource{}, false, errors.New("codeblocks comment has no file= attribute")
+		}
+		if value, found := attributes.Get("lines"); found {
+			lines = value
+		}
+		if value, found := attributes.Get("region"); found {
+			region = value
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(e.block.Position.Path), filepath.FromSlash(file))
+		}
+		return syncSource{path: file, lines: lines, region: region}, true, nil
+	}
+
+	if e.block.Attributes.Filename() == "" {
+		return syncSource{}, false, nil
+	}
+	path, err := model.ResolvePath(outputDirectory, e.sourceCode.Filename)
+	if err != nil {
+		return syncSource{}, false, err
+	}
+	return syncSource{path: path, lines: lines, region: region, byFilename: true}, true, nil
+}
+
+// markerBefore returns the attributes of a codeblocks HTML comment on the last non-blank line
+// before offset, ignoring blockquote markers.
+func markerBefore(source []byte, offset int) (string, bool) {
+	end := strings.LastIndexByte(string(source[:offset]), '\n')
+	for end >= 0 {
+		start := strings.LastIndexByte(string(source[:end]), '\n') + 1
+		line := strings.TrimSpace(strings.TrimLeft(string(source[start:end]), " \t>"))
+		if line != "" {
+			if match := syncMarker.FindStringSubmatch(line); match != nil {
+				return match[1], true
+			}
+			return "", false
+		}
+		end = start - 1
+	}
+	return "", false
+}
+
+// load reads the bound file and selects the configured lines or region.
+func (s syncSource) load() (string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+	if s.region != "" {
+		if content, err = selectRegion(content, s.region); err != nil {
+			return "", fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+	if s.lines != "" {
+		if content, err = selectLines(content, s.lines); err != nil {
+			return "", fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+	return content, nil
+}
+
+// selectLines returns the 1-based, inclusive line range spec of content: "3-10", "3", "3-" or "-10".
+func selectLines(content, spec string) (string, error) {
+	lines := splitLines(content)
+	from, to, found := strings.Cut(spec, "-")
+	if !found {
+		to = from
+	}
+	first, last := 1, len(lines)
+	var err error
+	if from != "" {
+		if first, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+			return "", fmt.Errorf("invalid lines=%s", spec)
+		}
+	}
+	if to != "" {
+		if last, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+			return "", fmt.Errorf("invalid lines=%s", spec)
+		}
+	}
+	if first < 1 || last > len(lines) || first > last {
+		return "", fmt.Errorf("lines=%s is outside the %d lines of the file", spec, len(lines))
+	}
+	return strings.Join(lines[first-1:last], ""), nil
+}
+
+// selectRegion returns the lines between the "#region name" and the matching "#endregion"
+// comments of content, in whatever comment syntax the language uses. Regions may be nested.
+func selectRegion(content, name string) (string, error) {
+	lines := splitLines(content)
+	start, depth := -1, 0
+	for i, line := range lines {
+		directive, label := regionDirective(line)
+		switch {
+		case start < 0:
+			if directive == "#region" && label == name {
+				start, depth = i+1, 1
+			}
+		case directive == "#region":
+			depth++
+		case directive == "#endregion":
+			depth--
+			if depth == 0 {
+				return strings.Join(lines[start:i], ""), nil
+			}
+		}
+	}
+	if start < 0 {
+		return "", fmt.Errorf("region %q not found", name)
+	}
+	return "", fmt.Errorf("region %q has no #endregion", name)
+}
+
+// regionDirective returns #region or #endregion and the label following it if line contains one.
+func regionDirective(line string) (string, string) {
+	for _, directive := range []string{"#endregion", "#region"} {
+		if i := strings.Index(line, directive); i >= 0 {
+			rest := line[i+len(directive):]
+			if rest != "" && !strings.ContainsAny(rest[:1], " \t\r\n") {
+				continue // e.g. #regional
+			}
+			label, _, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			// Block comment syntaxes close on the same line, e.g. <!-- #region name -->
+			label = strings.TrimSuffix(strings.TrimSuffix(label, "-->"), "*/")
+			return directive, label
+		}
+	}
+	return "", ""
+}
+
+// replaceBody returns the byte range of the body of a fenced block in source and the text to put
+// there for content: every line prefixed with the block's container indentation and ending with
+// the document's line ending. The fences themselves are not part of the range.
+func replaceBody(source []byte, block model.FencedCodeBlock, content string) (int, int, string, error) {
+	text := string(source)
+	opening := block.Position.StartOffset
+	lineStart := strings.LastIndexByte(text[:opening], '\n') + 1
+	bodyStart := len(text)
+	newline := "\n"
+	if i := strings.IndexByte(text[opening:], '\n'); i >= 0 {
+		bodyStart = opening + i + 1
+		if i > 0 && text[opening+i-1] == '\r' {
+			newline = "\r\n"
+		}
+	}
+
+	// The closing fence is the line holding the end of the block
+	bodyEnd := strings.LastIndexByte(text[:block.Position.EndOffset], '\n') + 1
+	fence := strings.Repeat(string(block.Fence.Char), block.Fence.Length)
+	if bodyEnd < bodyStart || !strings.HasPrefix(strings.TrimLeft(text[bodyEnd:block.Position.EndOffset], " \t>"), fence) {
+		return 0, 0, "", errors.New("the block has no closing fence")
+	}
+
+	// Continuation lines repeat blockquote markers and indent to the fence, e.g. "> - ```" gives ">   "
+	prefix := []byte(text[lineStart:opening])
+	for i, c := range prefix {
+		if c != '>' && c != '\t' {
+			prefix[i] = ' '
+		}
+	}
+
+	var sb strings.Builder
+	for _, line := range splitLines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(strings.TrimLeft(line, " \t"), fence) {
+			return 0, 0, "", fmt.Errorf("the file contains a line starting with %s, which would close the block; use a longer fence", fence)
+		}
+		if line == "" {
+			sb.WriteString(strings.TrimRight(string(prefix), " \t"))
+		} else {
+			sb.Write(prefix)
+			sb.WriteString(line)
+		}
+		sb.WriteString(newline)
+	}
+	return bodyStart, bodyEnd, sb.String(), nil
+}
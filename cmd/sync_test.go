@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// syncMarkdown syncs the blocks of markdown against the files in dir, which is both the
+// output directory and the directory of the Markdown document.
+func syncMarkdown(t *testing.T, dir, markdown string) (string, int, string) {
+	t.Helper()
+	document := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(document, []byte(markdown), 0644); err != nil {
+		t.Fatalf("Failed to write markdown: %v", err)
+	}
+	setConfig(t, map[string]any{"input": document, "output-directory": dir})
+	extractions, err := extractAll(nil)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	var warnings bytes.Buffer
+	updated, count, err := syncDocument([]byte(markdown), extractions, dir, &warnings)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	return string(updated), count, warnings.String()
+}
+
+func TestSyncDocument(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"hello.py": "print('fixed')\n\nprint('twice')\n",
+		"src/main.go": "package main\n\n" +
+			"func main() {\n" +
+			"\t// #region greet\n" +
+			"\tprintln(\"hi\")\n" +
+			"\t// #endregion\n" +
+			"}\n",
+		"notes.txt": "one\ntwo\nthree\nfour\n",
+	})
+
+	markdown := "# Guide\n\n" +
+		"1. Run:\n\n" +
+		"   ```python filename=\"hello.py\"\n" +
+		"   print('old')\n" +
+		"   ```\n\n" +
+		"<!-- codeblocks file=\"src/main.go\" region=greet -->\n" +
+		"> ~~~~go\n" +
+		"> println(\"old\")\n" +
+		"> ~~~~\n\n" +
+		"```text filename=\"notes.txt\" lines=2-3\n" +
+		"stale\n" +
+		"```\n\n" +
+		"```go filename=\"missing.go\"\n" +
+		"package missing\n" +
+		"```\n\n" +
+		"```go\n" +
+		"package unbound\n" +
+		"```\n"
+
+	updated, count, warnings := syncMarkdown(t, dir, markdown)
+
+	expected := "# Guide\n\n" +
+		"1. Run:\n\n" +
+		"   ```python filename=\"hello.py\"\n" +
+		"   print('fixed')\n" +
+		"\n" + // Blank lines get no trailing indentation
+		"   print('twice')\n" +
+		"   ```\n\n" +
+		"<!-- codeblocks file=\"src/main.go\" region=greet -->\n" +
+		"> ~~~~go\n" +
+		"> \tprintln(\"hi\")\n" +
+		"> ~~~~\n\n" +
+		"```text filename=\"notes.txt\" lines=2-3\n" +
+		"two\nthree\n" +
+		"```\n\n" +
+		"```go filename=\"missing.go\"\n" +
+		"package missing\n" +
+		"```\n\n" +
+		"```go\n" +
+		"package unbound\n" +
+		"```\n"
+	if updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 updated blocks, got %d", count)
+	}
+	if !strings.Contains(warnings, "missing.go does not exist") {
+		t.Errorf("Expected a warning about missing.go, got %q", warnings)
+	}
+
+	// Syncing again changes nothing
+	if again, count, _ := syncMarkdown(t, dir, updated); again != updated || count != 0 {
+		t.Errorf("Expected a second sync to be a no-op, got %d changes:\n%s", count, again)
+	}
+}
+
+func TestSyncDocumentPreservesLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.sh": "echo new\n"})
+
+	updated, count, _ := syncMarkdown(t, dir, "Intro\r\n\r\n```sh filename=\"a.sh\"\r\necho old\r\n```\r\nOutro")
+	if expected := "Intro\r\n\r\n```sh filename=\"a.sh\"\r\necho new\r\n```\r\nOutro"; updated != expected || count != 1 {
+		t.Errorf("Expected %q, got %q (%d changes)", expected, updated, count)
+	}
+}
+
+func TestSyncDocumentRejectsFenceInContent(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"README.md": "# Nested\n\n```go\nx\n```\n"})
+
+	document := filepath.Join(dir, "doc.md")
+	markdown := "```markdown filename=\"README.md\"\nold\n```\n"
+	if err := os.WriteFile(document, []byte(markdown), 0644); err != nil {
+		t.Fatalf("Failed to write markdown: %v", err)
+	}
+	setConfig(t, map[string]any{"input": document, "output-directory": dir})
+	extractions, err := extractAll(nil)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if _, _, err := syncDocument([]byte(markdown), extractions, dir, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "longer fence") {
+		t.Errorf("Expected an error about the fence, got %v", err)
+	}
+}
+
+func TestSelectRegionAndLines(t *testing.T) {
+	content := "a\n# #region outer\nb\n# #region inner\nc\n# #endregion\nd\n# #endregion\ne\n"
+
+	if region, err := selectRegion(content, "outer"); err != nil || region != "b\n# #region inner\nc\n# #endregion\nd\n" {
+		t.Errorf("Unexpected outer region %q, %v", region, err)
+	}
+	if region, err := selectRegion(content, "inner"); err != nil || region != "c\n" {
+		t.Errorf("Unexpected inner region %q, %v", region, err)
+	}
+	if _, err := selectRegion(content, "missing"); err == nil {
+		t.Error("Expected an error for a missing region")
+	}
+
+	tests := map[string]string{"2": "b\n", "1-2": "a\nb\n", "4-": "d\ne\n", "-1": "a\n"}
+	for spec, expected := range tests {
+		if selected, err := selectLines("a\nb\nc\nd\ne\n", spec); err != nil || selected != expected {
+			t.Errorf("selectLines(%q) = %q, %v, want %q", spec, selected, err, expected)
+		}
+	}
+	for _, spec := range []string{"0-2", "4-9", "3-2", "x"} {
+		if _, err := selectLines("a\nb\nc\nd\ne\n", spec); err == nil {
+			t.Errorf("Expected selectLines(%q) to fail", spec)
+		}
+	}
+}
+
+func TestSyncDocumentSkipsTangledBlocks(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+
+	markdown := "```go chunk=main filename=\"main.go\"\npackage main\n\n<<body>>\n```\n\n" +
+		"```go chunk=body\nfunc main() {}\n```\n"
+	updated, count, warnings := syncMarkdown(t, dir, markdown)
+	if updated != markdown || count != 0 {
+		t.Errorf("Expected the tangled block to be left alone, got:\n%s", updated)
+	}
+	if !strings.Contains(warnings, "assembled from chunks") {
+		t.Errorf("Expected a warning about the tangled block, got %q", warnings)
+	}
+}
@@ -0,0 +1,240 @@
+// Package extract finds code blocks in Markdown documents.
+//
+// It is the library behind the codeblocks command and can be embedded in other Go tooling:
+//
+//	extractor := extract.New(extract.WithUntagged(true))
+//	codeBlocks, err := extractor.ExtractFile("README.md")
+package extract
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Filter decides whether an extracted block is kept.
+type Filter func(block model.FencedCodeBlock) bool
+
+// Option configures an Extractor.
+type Option func(*Extractor)
+
+// Extractor parses Markdown and collects its code blocks in document order.
+type Extractor struct {
+	includeUntagged bool
+	includeIndented bool
+	includeEmpty    bool
+	filters         []Filter
+	skipped         func(model.FencedCodeBlock, SkipReason)
+	fallback        func(model.FencedCodeBlock) string
+	extensions      []goldmark.Extender
+}
+
+// SkipReason says why a code block was not extracted.
+type SkipReason string
+
+// Reasons passed to the WithSkipped callback.
+const (
+	SkipUntagged SkipReason = "untagged" // a fenced block without a language, see WithUntagged
+	SkipEmpty    SkipReason = "empty"    // a block without content, see WithEmpty
+)
+
+// New returns an Extractor configured with options. By default only fenced code blocks with a
+// language and non-empty content are extracted.
+func New(options ...Option) *Extractor {
+	e := &Extractor{}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// WithUntagged includes fenced code blocks without a language.
+func WithUntagged(include bool) Option {
+	return func(e *Extractor) {
+		e.includeUntagged = include
+	}
+}
+
+// WithEmpty includes code blocks without content.
+func WithEmpty(include bool) Option {
+	return func(e *Extractor) {
+		e.includeEmpty = include
+	}
+}
+
+// WithSkipped registers a function called with every block dropped because it is untagged or
+// empty, so that callers can tell users what was ignored. Blocks rejected by filters are not reported.
+func WithSkipped(skipped func(block model.FencedCodeBlock, reason SkipReason)) Option {
+	return func(e *Extractor) {
+		e.skipped = skipped
+	}
+}
+
+// WithFallbackLanguage replaces the language of included blocks that have none (untagged fenced
+// blocks and indented blocks) or whose language is not known to model, with the language returned
+// by fallback, before filters see them. Fallback returns block.Language to keep an unknown tag.
+func WithFallbackLanguage(fallback func(block model.FencedCodeBlock) string) Option {
+	return func(e *Extractor) {
+		e.fallback = fallback
+	}
+}
+
+// WithIndented includes indented (non-fenced) code blocks, which have no language and are marked Indented.
+func WithIndented(include bool) Option {
+	return func(e *Extractor) {
+		e.includeIndented = include
+	}
+}
+
+// WithFilter adds a filter; a block is kept only if every filter returns true.
+func WithFilter(filter Filter) Option {
+	return func(e *Extractor) {
+		e.filters = append(e.filters, filter)
+	}
+}
+
+// WithExtensions adds goldmark extensions to the Markdown parser, e.g. extension.GFM.
+func WithExtensions(extensions ...goldmark.Extender) Option {
+	return func(e *Extractor) {
+		e.extensions = append(e.extensions, extensions...)
+	}
+}
+
+// Extract returns the code blocks in source.
+func (e *Extractor) Extract(source []byte) ([]model.FencedCodeBlock, error) {
+	return e.extract(source, "")
+}
+
+// ExtractFile returns the code blocks in the Markdown file at path. Block positions record the path.
+func (e *Extractor) ExtractFile(path string) ([]model.FencedCodeBlock, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return e.extract(source, path)
+}
+
+// ExtractReader returns the code blocks in the Markdown read from r.
+func (e *Extractor) ExtractReader(r io.Reader) ([]model.FencedCodeBlock, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return e.extract(source, "")
+}
+
+func (e *Extractor) extract(source []byte, path string) ([]model.FencedCodeBlock, error) {
+	parser := goldmark.DefaultParser()
+	if len(e.extensions) > 0 {
+		parser = goldmark.New(goldmark.WithExtensions(e.extensions...)).Parser()
+	}
+	node := parser.Parse(text.NewReader(source))
+
+	var codeBlocks []model.FencedCodeBlock
+	var headings []model.Heading
+	cursor := 0 // offset past everything visited so far, for locating bare fences
+	err := ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if node.Type() == ast.TypeBlock && node.Lines().Len() > 0 {
+			cursor = max(cursor, node.Lines().At(node.Lines().Len()-1).Stop)
+		}
+
+		var codeBlock model.FencedCodeBlock
+		switch n := node.(type) {
+		case *ast.Heading:
+			// Keep the stack of enclosing headings: a heading closes any open heading of the same or deeper level
+			for len(headings) > 0 && headings[len(headings)-1].Level >= n.Level {
+				headings = headings[:len(headings)-1]
+			}
+			headings = append(headings, model.Heading{Text: headingText(n, source), Level: n.Level})
+			return ast.WalkContinue, nil
+		case *ast.FencedCodeBlock:
+			if n.Info != nil {
+				segment := n.Info.Segment
+				codeBlock.Language, codeBlock.Attributes = model.ParseInfo(string(source[segment.Start:segment.Stop]))
+			}
+			codeBlock.Position, codeBlock.Fence = fencePosition(n, source, path, cursor)
+			cursor = max(cursor, codeBlock.Position.EndOffset)
+		case *ast.CodeBlock:
+			if !e.includeIndented {
+				return ast.WalkSkipChildren, nil
+			}
+			codeBlock.Indented = true
+			codeBlock.Position = indentedPosition(n, source, path)
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		codeBlock.Content = content(node, source)
+		codeBlock.Headings = append([]model.Heading(nil), headings...)
+		switch {
+		case codeBlock.Language == "" && !codeBlock.Indented && !e.includeUntagged:
+			e.skip(codeBlock, SkipUntagged)
+			return ast.WalkSkipChildren, nil
+		case codeBlock.Content == "" && !e.includeEmpty:
+			e.skip(codeBlock, SkipEmpty)
+			return ast.WalkSkipChildren, nil
+		}
+		if e.fallback != nil && !model.KnownLanguage(codeBlock.Language) {
+			codeBlock.Language = e.fallback(codeBlock)
+		}
+		for _, filter := range e.filters {
+			if !filter(codeBlock) {
+				return ast.WalkSkipChildren, nil
+			}
+		}
+		codeBlocks = append(codeBlocks, codeBlock)
+
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codeBlocks, nil
+}
+
+// skip reports a dropped block to the WithSkipped callback, if any.
+func (e *Extractor) skip(block model.FencedCodeBlock, reason SkipReason) {
+	if e.skipped != nil {
+		e.skipped(block, reason)
+	}
+}
+
+// content joins the lines of a code block.
+func content(node ast.Node, source []byte) string {
+	var sb strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		sb.Write(line.Value(source))
+	}
+	return sb.String()
+}
+
+// headingText returns the plain text of a heading, without any inline markup.
+func headingText(heading *ast.Heading, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(heading, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			switch n := node.(type) {
+			case *ast.Text:
+				sb.Write(n.Value(source))
+				if n.SoftLineBreak() {
+					sb.WriteByte(' ')
+				}
+			case *ast.String:
+				sb.Write(n.Value)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
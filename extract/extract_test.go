@@ -0,0 +1,291 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/yuin/goldmark/extension"
+)
+
+func TestExtractDefaults(t *testing.T) {
+	markdown := "```go\npackage main\n```\n\n" +
+		"```\nuntagged\n```\n\n" +
+		"```python\n```\n\n" +
+		"    indented code\n\n" +
+		"```js title=\"app.js\"\nconsole.log(1)\n```\n"
+
+	codeBlocks, err := New().Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 2 {
+		t.Fatalf("Expected 2 code blocks, got %d", len(codeBlocks))
+	}
+	if codeBlocks[0].Language != "go" || codeBlocks[0].Content != "package main\n" {
+		t.Errorf("Unexpected first block %+v", codeBlocks[0])
+	}
+	if codeBlocks[1].Language != "js" || codeBlocks[1].Attributes.Filename() != "app.js" {
+		t.Errorf("Unexpected second block %+v", codeBlocks[1])
+	}
+}
+
+func TestExtractOptions(t *testing.T) {
+	markdown := "```go\npackage main\n```\n\n" +
+		"```\nuntagged\n```\n\n" +
+		"Paragraph\n\n" +
+		"    indented code\n\n" +
+		"```python\nprint(1)\n```\n"
+
+	tests := []struct {
+		name      string
+		options   []Option
+		languages []string
+		indented  []bool
+	}{
+		{"default", nil, []string{"go", "python"}, []bool{false, false}},
+		{"untagged", []Option{WithUntagged(true)}, []string{"go", "", "python"}, []bool{false, false, false}},
+		{"indented", []Option{WithIndented(true)}, []string{"go", "", "python"}, []bool{false, true, false}},
+		{
+			"filter",
+			[]Option{WithFilter(func(block model.FencedCodeBlock) bool { return block.Language != "go" })},
+			[]string{"python"},
+			[]bool{false},
+		},
+		{"extensions", []Option{WithExtensions(extension.GFM)}, []string{"go", "python"}, []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codeBlocks, err := New(tt.options...).Extract([]byte(markdown))
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if len(codeBlocks) != len(tt.languages) {
+				t.Fatalf("Expected %d code blocks, got %d", len(tt.languages), len(codeBlocks))
+			}
+			for i, codeBlock := range codeBlocks {
+				if codeBlock.Language != tt.languages[i] {
+					t.Errorf("Block %d: Expected language %q, got %q", i, tt.languages[i], codeBlock.Language)
+				}
+				if codeBlock.Indented != tt.indented[i] {
+					t.Errorf("Block %d: Expected indented %v, got %v", i, tt.indented[i], codeBlock.Indented)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractIndentedPosition(t *testing.T) {
+	markdown := "Paragraph\n\n    line one\n    line two\n"
+
+	codeBlocks, err := New(WithIndented(true)).Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 1 {
+		t.Fatalf("Expected 1 code block, got %d", len(codeBlocks))
+	}
+	codeBlock := codeBlocks[0]
+	if codeBlock.Content != "line one\nline two\n" {
+		t.Errorf("Unexpected content %q", codeBlock.Content)
+	}
+	if codeBlock.Position.StartLine != 3 || codeBlock.Position.EndLine != 4 {
+		t.Errorf("Expected lines 3-4, got %d-%d", codeBlock.Position.StartLine, codeBlock.Position.EndLine)
+	}
+}
+
+func TestExtractFileAndReader(t *testing.T) {
+	markdown := "# Title\n\n```go\npackage main\n```\n"
+	path := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(path, []byte(markdown), 0644); err != nil {
+		t.Fatalf("Failed to write markdown: %v", err)
+	}
+
+	codeBlocks, err := New().ExtractFile(path)
+	if err != nil {
+		t.Fatalf("Failed to extract file: %v", err)
+	}
+	if len(codeBlocks) != 1 || codeBlocks[0].Position.Path != path {
+		t.Fatalf("Expected 1 block with path %s, got %+v", path, codeBlocks)
+	}
+	if codeBlocks[0].Position.String() != path+":3:1" {
+		t.Errorf("Unexpected position string %s", codeBlocks[0].Position)
+	}
+
+	codeBlocks, err = New().ExtractReader(strings.NewReader(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract reader: %v", err)
+	}
+	if len(codeBlocks) != 1 || codeBlocks[0].Position.Path != "" {
+		t.Fatalf("Expected 1 block without path, got %+v", codeBlocks)
+	}
+
+	if _, err := New().ExtractFile(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestBlockPositions(t *testing.T) {
+	markdown := "# Guide\n" + // 1
+		"\n" + // 2
+		"## Install\n" + // 3
+		"\n" + // 4
+		"```bash\n" + // 5
+		"make install\n" + // 6
+		"```\n" + // 7
+		"\n" + // 8
+		"- step one\n" + // 9
+		"\n" + // 10
+		"  ~~~~go title=\"main.go\"\n" + // 11
+		"  package main\n" + // 12
+		"  ~~~~~\n" + // 13
+		"\n" + // 14
+		"# Reference\n" + // 15
+		"\n" + // 16
+		"> ```json\n" + // 17
+		"> {}\n" + // 18
+		"> ```\n" + // 19
+		"\n" + // 20
+		"```python\n" + // 21
+		"print('unclosed')\n" // 22
+
+	codeBlocks, err := New().Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 4 {
+		t.Fatalf("Expected 4 code blocks, got %d", len(codeBlocks))
+	}
+
+	tests := []struct {
+		position model.Position
+		fence    model.Fence
+		headings []model.Heading
+	}{
+		{
+			model.Position{StartLine: 5, StartColumn: 1, EndLine: 7, EndColumn: 3, StartOffset: 21, EndOffset: 45},
+			model.Fence{Char: '`', Length: 3, Indent: 0},
+			[]model.Heading{{Text: "Guide", Level: 1}, {Text: "Install", Level: 2}},
+		},
+		{
+			model.Position{StartLine: 11, StartColumn: 3, EndLine: 13, EndColumn: 7},
+			model.Fence{Char: '~', Length: 4, Indent: 2},
+			[]model.Heading{{Text: "Guide", Level: 1}, {Text: "Install", Level: 2}},
+		},
+		{
+			model.Position{StartLine: 17, StartColumn: 3, EndLine: 19, EndColumn: 5},
+			model.Fence{Char: '`', Length: 3, Indent: 2},
+			[]model.Heading{{Text: "Reference", Level: 1}},
+		},
+		{
+			model.Position{StartLine: 21, StartColumn: 1, EndLine: 22, EndColumn: 17},
+			model.Fence{Char: '`', Length: 3, Indent: 0},
+			[]model.Heading{{Text: "Reference", Level: 1}},
+		},
+	}
+
+	for i, tt := range tests {
+		position := codeBlocks[i].Position
+		if position.StartLine != tt.position.StartLine || position.StartColumn != tt.position.StartColumn ||
+			position.EndLine != tt.position.EndLine || position.EndColumn != tt.position.EndColumn {
+			t.Errorf("Block %d: Expected position %d:%d-%d:%d, got %d:%d-%d:%d", i,
+				tt.position.StartLine, tt.position.StartColumn, tt.position.EndLine, tt.position.EndColumn,
+				position.StartLine, position.StartColumn, position.EndLine, position.EndColumn)
+		}
+		if tt.position.EndOffset != 0 && (position.StartOffset != tt.position.StartOffset || position.EndOffset != tt.position.EndOffset) {
+			t.Errorf("Block %d: Expected offsets %d-%d, got %d-%d", i,
+				tt.position.StartOffset, tt.position.EndOffset, position.StartOffset, position.EndOffset)
+		}
+		if got := markdown[position.StartOffset : position.StartOffset+tt.fence.Length]; strings.Trim(got, "`~") != "" {
+			t.Errorf("Block %d: StartOffset does not point at the fence, got %q", i, got)
+		}
+		if codeBlocks[i].Fence != tt.fence {
+			t.Errorf("Block %d: Expected fence %+v, got %+v", i, tt.fence, codeBlocks[i].Fence)
+		}
+		if !reflect.DeepEqual(codeBlocks[i].Headings, tt.headings) {
+			t.Errorf("Block %d: Expected headings %v, got %v", i, tt.headings, codeBlocks[i].Headings)
+		}
+	}
+}
+
+func TestExtractEmptyAndSkipped(t *testing.T) {
+	markdown := "```go\npackage main\n```\n\n" +
+		"```\nuntagged\n```\n\n" +
+		"Text with ``` inline\n\n" +
+		"```\n```\n\n" +
+		"> ~~~python\n> ~~~\n"
+
+	type skip struct {
+		line   int
+		reason SkipReason
+	}
+	var skipped []skip
+	record := WithSkipped(func(block model.FencedCodeBlock, reason SkipReason) {
+		skipped = append(skipped, skip{block.Position.StartLine, reason})
+	})
+
+	codeBlocks, err := New(record).Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 1 {
+		t.Fatalf("Expected 1 code block, got %d", len(codeBlocks))
+	}
+	expected := []skip{{5, SkipUntagged}, {11, SkipUntagged}, {14, SkipEmpty}}
+	if !reflect.DeepEqual(skipped, expected) {
+		t.Errorf("Expected skipped blocks %+v, got %+v", expected, skipped)
+	}
+
+	skipped = nil
+	codeBlocks, err = New(WithUntagged(true), WithEmpty(true), record).Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(codeBlocks) != 4 || len(skipped) != 0 {
+		t.Fatalf("Expected 4 code blocks and none skipped, got %d and %+v", len(codeBlocks), skipped)
+	}
+
+	// The bare empty fence is located even though goldmark records nothing for it
+	empty := codeBlocks[2]
+	if empty.Content != "" || empty.Position.StartLine != 11 || empty.Position.EndLine != 12 || empty.Fence.Length != 3 {
+		t.Errorf("Unexpected empty block %+v", empty)
+	}
+	if quoted := codeBlocks[3]; quoted.Language != "python" || quoted.Position.StartColumn != 3 || quoted.Fence.Char != '~' {
+		t.Errorf("Unexpected quoted block %+v", quoted)
+	}
+}
+
+func TestExtractFallbackLanguage(t *testing.T) {
+	markdown := "```\n#!/bin/sh\necho hi\n```\n\n" +
+		"Paragraph\n\n" +
+		"    indented code\n\n" +
+		"```go\npackage main\n```\n"
+
+	fallback := WithFallbackLanguage(func(block model.FencedCodeBlock) string {
+		if block.Indented {
+			return "text"
+		}
+		return model.ShebangLanguage(block.Content)
+	})
+	// The fallback runs before filters, so they see the assigned language
+	filter := WithFilter(func(block model.FencedCodeBlock) bool { return block.Language != "go" })
+
+	codeBlocks, err := New(WithUntagged(true), WithIndented(true), fallback, filter).Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	var languages []string
+	for _, codeBlock := range codeBlocks {
+		languages = append(languages, codeBlock.Language)
+	}
+	if expected := []string{"sh", "text"}; !reflect.DeepEqual(languages, expected) {
+		t.Errorf("Expected languages %v, got %v", expected, languages)
+	}
+	if !codeBlocks[1].Indented {
+		t.Error("Expected the indented block to be marked indented")
+	}
+}
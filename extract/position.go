@@ -0,0 +1,152 @@
+package extract
+
+import (
+	"bytes"
+
+	"github.com/spandigitial/codeblocks/model"
+	"github.com/yuin/goldmark/ast"
+)
+
+// fencePosition locates the opening and closing fences of a fenced code block in source.
+// goldmark only records the info string and content lines, so the fences are found by scanning
+// the lines around them. A block with neither, such as an empty block opened by a bare fence, is
+// found by scanning forward from offset from, which must be past everything before the block.
+// It returns zero values if no fence is found.
+func fencePosition(fcb *ast.FencedCodeBlock, source []byte, path string, from int) (model.Position, model.Fence) {
+	lines := fcb.Lines()
+	fenceStart := -1
+	if fcb.Info != nil {
+		// Walk back from the info string over any whitespace and the run of fence characters
+		i := fcb.Info.Segment.Start
+		for i > 0 && (source[i-1] == ' ' || source[i-1] == '\t') {
+			i--
+		}
+		for i > 0 && (source[i-1] == '`' || source[i-1] == '~') {
+			i--
+		}
+		fenceStart = i
+	} else if lines.Len() > 0 {
+		// The opening fence is on the line before the first content line
+		if start := lineStartAt(source, lines.At(0).Start); start > 0 {
+			previous := lineStartAt(source, start-1)
+			if i := bytes.IndexAny(source[previous:start], "`~"); i >= 0 {
+				fenceStart = previous + i
+			}
+		}
+	} else {
+		fenceStart = bareFenceAfter(source, from)
+	}
+	if fenceStart < 0 {
+		return model.Position{Path: path}, model.Fence{}
+	}
+
+	fence := model.Fence{Char: source[fenceStart], Indent: fenceStart - lineStartAt(source, fenceStart)}
+	for i := fenceStart; i < len(source) && source[i] == fence.Char; i++ {
+		fence.Length++
+	}
+
+	position := model.Position{
+		Path:        path,
+		StartLine:   lineAt(source, fenceStart),
+		StartColumn: fence.Indent + 1,
+		StartOffset: fenceStart,
+	}
+
+	// The closing fence, if any, is on the line after the content
+	after := lineEndAt(source, fenceStart)
+	if lines.Len() > 0 {
+		after = lines.At(lines.Len() - 1).Stop
+	}
+	closingEnd := -1
+	if after < len(source) {
+		line := source[after:lineEndAt(source, after)]
+		if i := bytes.IndexByte(line, fence.Char); i >= 0 && len(bytes.Trim(line[:i], " \t>")) == 0 {
+			run := i
+			for run < len(line) && line[run] == fence.Char {
+				run++
+			}
+			if run-i >= fence.Length && len(bytes.TrimSpace(line[run:])) == 0 {
+				closingEnd = after + run
+			}
+		}
+	}
+	if closingEnd < 0 {
+		// Unclosed: the block ends with its last line
+		closingEnd = after
+		for closingEnd > fenceStart && (source[closingEnd-1] == '\n' || source[closingEnd-1] == '\r') {
+			closingEnd--
+		}
+	}
+	position.EndOffset = closingEnd
+	position.EndLine = lineAt(source, closingEnd-1)
+	position.EndColumn = closingEnd - lineStartAt(source, closingEnd-1)
+
+	return position, fence
+}
+
+// bareFenceAfter returns the offset of the first fence without an info string on a line starting
+// at or after from, or -1 if there is none.
+func bareFenceAfter(source []byte, from int) int {
+	start := from
+	if start > 0 && source[start-1] != '\n' {
+		start = lineEndAt(source, start)
+	}
+	for start < len(source) {
+		end := lineEndAt(source, start)
+		line := source[start:end]
+		i := 0
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t' || line[i] == '>') {
+			i++
+		}
+		run := i
+		for run < len(line) && (line[run] == '`' || line[run] == '~') && line[run] == line[i] {
+			run++
+		}
+		if run-i >= 3 && len(bytes.TrimSpace(line[run:])) == 0 {
+			return start + i
+		}
+		start = end
+	}
+	return -1
+}
+
+// indentedPosition locates an indented code block, which spans its content lines.
+func indentedPosition(cb *ast.CodeBlock, source []byte, path string) model.Position {
+	lines := cb.Lines()
+	if lines.Len() == 0 {
+		return model.Position{Path: path}
+	}
+	start := lineStartAt(source, lines.At(0).Start)
+	end := lines.At(lines.Len() - 1).Stop
+	for end > start && (source[end-1] == '\n' || source[end-1] == '\r') {
+		end--
+	}
+	return model.Position{
+		Path:        path,
+		StartLine:   lineAt(source, start),
+		StartColumn: 1,
+		EndLine:     lineAt(source, end-1),
+		EndColumn:   end - lineStartAt(source, end-1),
+		StartOffset: start,
+		EndOffset:   end,
+	}
+}
+
+// lineStartAt returns the offset of the start of the line containing offset.
+func lineStartAt(source []byte, offset int) int {
+	return bytes.LastIndexByte(source[:offset], '\n') + 1
+}
+
+// lineEndAt returns the offset just past the newline ending the line containing offset,
+// or the length of source for the last line.
+func lineEndAt(source []byte, offset int) int {
+	if i := bytes.IndexByte(source[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(source)
+}
+
+// lineAt returns the 1-based line number of the byte offset in source.
+func lineAt(source []byte, offset int) int {
+	return bytes.Count(source[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// sectionMatcher matches a single heading of a section path.
+type sectionMatcher func(heading model.Heading) bool
+
+// Section returns a filter keeping blocks nested under the section named by selector.
+//
+// A selector is a heading text or slug ("Usage", "getting-started"), or a path of them separated by
+// ">" ("Usage > Examples"), which matches headings nested in that order, not necessarily at adjacent
+// levels. A component written as /pattern/ is a regular expression matched against the heading text.
+// Text components are compared by slug, so case and punctuation do not matter.
+func Section(selector string) (Filter, error) {
+	matchers, err := parseSection(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(block model.FencedCodeBlock) bool {
+		next := 0
+		for _, heading := range block.Headings {
+			if next < len(matchers) && matchers[next](heading) {
+				next++
+			}
+		}
+		return next == len(matchers)
+	}, nil
+}
+
+// parseSection splits a selector into one matcher per path component.
+func parseSection(selector string) ([]sectionMatcher, error) {
+	var matchers []sectionMatcher
+	rest := strings.TrimSpace(selector)
+	for rest != "" {
+		var component string
+		if strings.HasPrefix(rest, "/") {
+			end := closingSlash(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid section %q: unterminated regular expression", selector)
+			}
+			pattern, err := regexp.Compile(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid section %q: %w", selector, err)
+			}
+			matchers = append(matchers, func(heading model.Heading) bool { return pattern.MatchString(heading.Text) })
+			rest = strings.TrimSpace(rest[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, ">") {
+				return nil, fmt.Errorf("invalid section %q: expected > after regular expression", selector)
+			}
+		} else {
+			component, rest, _ = strings.Cut(rest, ">")
+			slug := model.Slugify(component)
+			if slug == "" {
+				return nil, fmt.Errorf("invalid section %q: empty heading", selector)
+			}
+			matchers = append(matchers, func(heading model.Heading) bool { return heading.Slug() == slug })
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ">"))
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("invalid section %q: empty selector", selector)
+	}
+	return matchers, nil
+}
+
+// closingSlash returns the index of the slash ending a /pattern/ component, skipping escaped slashes.
+func closingSlash(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"testing"
+)
+
+func TestSection(t *testing.T) {
+	markdown := "# Install\n\n```bash\nmake install\n```\n\n" +
+		"# Usage\n\n```bash\ncodeblocks -h\n```\n\n" +
+		"## Examples\n\n```go\npackage example\n```\n\n" +
+		"### Advanced Examples\n\n```go\npackage advanced\n```\n\n" +
+		"# Changelog\n\n## Examples\n\n```diff\n+ added\n```\n"
+
+	tests := []struct {
+		selector string
+		contents []string
+	}{
+		{"Usage", []string{"codeblocks -h\n", "package example\n", "package advanced\n"}},
+		{"usage", []string{"codeblocks -h\n", "package example\n", "package advanced\n"}},
+		{"Examples", []string{"package example\n", "package advanced\n", "+ added\n"}},
+		{"Usage > Examples", []string{"package example\n", "package advanced\n"}},
+		{"Usage>Advanced Examples", []string{"package advanced\n"}},
+		{"advanced-examples", []string{"package advanced\n"}},
+		{"Changelog > Examples", []string{"+ added\n"}},
+		{"/^Inst/", []string{"make install\n"}},
+		{"Usage > /Adv.*/", []string{"package advanced\n"}},
+		{"Examples > Usage", nil},
+		{"Missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			filter, err := Section(tt.selector)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			codeBlocks, err := New(WithFilter(filter)).Extract([]byte(markdown))
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			if len(codeBlocks) != len(tt.contents) {
+				t.Fatalf("Expected %d code blocks, got %d", len(tt.contents), len(codeBlocks))
+			}
+			for i, codeBlock := range codeBlocks {
+				if codeBlock.Content != tt.contents[i] {
+					t.Errorf("Block %d: Expected content %q, got %q", i, tt.contents[i], codeBlock.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestSectionErrors(t *testing.T) {
+	for _, selector := range []string{"", " > ", "/unterminated", "/(/", "/a/ b"} {
+		if _, err := Section(selector); err == nil {
+			t.Errorf("Expected an error for selector %q", selector)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spandigitial/codeblocks/model"
+)
+
+// chunkReference matches a noweb reference line such as "    <<parse arguments>>".
+var chunkReference = regexp.MustCompile(`^([ \t]*)<<([^<>]+)>>[ \t]*\r?\n?$`)
+
+// Tangle assembles literate-programming chunks. A block with a chunk= attribute defines, or appends
+// to, the chunk of that name; blocks sharing a name are concatenated in document order. A line
+// consisting only of a <<name>> reference is replaced by the expansion of that chunk, with the
+// reference's indentation added to every line.
+//
+// Chunk blocks are fragments and are dropped from the result, except that a chunk with a filename
+// attribute on one of its blocks is returned as a single block carrying the whole expansion, in
+// place of the chunk's first block. Other blocks are returned with their references expanded.
+// Undefined references and cycles are errors that name the line of the offending reference.
+//
+// Blocks are returned unchanged if none of them declares a chunk, so documents that do not use
+// chunks never have <<...>> lines interpreted.
+func Tangle(blocks []model.FencedCodeBlock) ([]model.FencedCodeBlock, error) {
+	t := tangler{chunks: make(map[string][]model.FencedCodeBlock)}
+	for _, block := range blocks {
+		if name, found := chunkName(block); found {
+			t.chunks[name] = append(t.chunks[name], block)
+		}
+	}
+	if len(t.chunks) == 0 {
+		return blocks, nil
+	}
+
+	var tangled []model.FencedCodeBlock
+	emitted := make(map[string]bool)
+	for _, block := range blocks {
+		name, isChunk := chunkName(block)
+		if !isChunk {
+			content, err := t.expandBlock(block, nil)
+			if err != nil {
+				return nil, err
+			}
+			block.Content = content
+			tangled = append(tangled, block)
+			continue
+		}
+		if emitted[name] {
+			continue
+		}
+		emitted[name] = true
+
+		root, written := t.root(name)
+		if !written {
+			continue
+		}
+		content, err := t.expand(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		root.Content = content
+		tangled = append(tangled, root)
+	}
+	return tangled, nil
+}
+
+// chunkName returns the name declared by a block's chunk= attribute.
+func chunkName(block model.FencedCodeBlock) (string, bool) {
+	name, found := block.Attributes.Get("chunk")
+	name = strings.TrimSpace(name)
+	return name, found && name != ""
+}
+
+// tangler holds the chunks of a document while they are expanded.
+type tangler struct {
+	chunks map[string][]model.FencedCodeBlock
+}
+
+// root returns the block of the named chunk that has a filename attribute, if any.
+func (t tangler) root(name string) (model.FencedCodeBlock, bool) {
+	for _, block := range t.chunks[name] {
+		if block.Attributes.Filename() != "" {
+			return block, true
+		}
+	}
+	return model.FencedCodeBlock{}, false
+}
+
+// expand returns the content of every block of the named chunk, with references expanded.
+// Stack holds the chunks being expanded, outermost first, to detect cycles.
+func (t tangler) expand(name string, stack []string) (string, error) {
+	stack = append(stack, name)
+	var sb strings.Builder
+	for _, block := range t.chunks[name] {
+		content, err := t.expandBlock(block, stack)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(content)
+	}
+	return sb.String(), nil
+}
+
+// expandBlock returns the content of block with every reference line replaced by its expansion.
+func (t tangler) expandBlock(block model.FencedCodeBlock, stack []string) (string, error) {
+	firstLine := block.Position.StartLine + 1 // the line after the opening fence
+	if block.Indented {
+		firstLine = block.Position.StartLine
+	}
+
+	var sb strings.Builder
+	for i, line := range strings.SplitAfter(block.Content, "\n") {
+		match := chunkReference.FindStringSubmatch(line)
+		if match == nil {
+			sb.WriteString(line)
+			continue
+		}
+		indent, name := match[1], strings.TrimSpace(match[2])
+		location := fmt.Sprintf("%s:%d", positionPath(block.Position), firstLine+i)
+
+		if _, defined := t.chunks[name]; !defined {
+			return "", fmt.Errorf("%s: undefined chunk <<%s>>", location, name)
+		}
+		for j, outer := range stack {
+			if outer == name {
+				cycle := append(append([]string(nil), stack[j:]...), name)
+				return "", fmt.Errorf("%s: chunk <<%s>> references itself: %s", location, name, strings.Join(cycle, " > "))
+			}
+		}
+
+		expansion, err := t.expand(name, stack)
+		if err != nil {
+			return "", err
+		}
+		for _, expanded := range strings.SplitAfter(expansion, "\n") {
+			if strings.TrimSpace(expanded) != "" {
+				sb.WriteString(indent)
+			}
+			sb.WriteString(expanded)
+		}
+		if !strings.HasSuffix(expansion, "\n") && strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// positionPath returns the document of a position as Position.String shows it.
+func positionPath(position model.Position) string {
+	if position.Path == "" {
+		return "<stdin>"
+	}
+	return position.Path
+}
@@ -0,0 +1,124 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTangle(t *testing.T) {
+	markdown := "# Design\n\n" +
+		"```go chunk=main filename=\"main.go\"\n" +
+		"package main\n\n" +
+		"func main() {\n" +
+		"\t<<parse arguments>>\n" +
+		"\trun()\n" +
+		"}\n" +
+		"```\n\n" +
+		"## Arguments\n\n" +
+		"```go chunk=\"parse arguments\"\n" +
+		"args := os.Args[1:]\n" +
+		"if len(args) == 0 {\n" +
+		"\t<<usage>>\n" +
+		"}\n" +
+		"```\n\n" +
+		"```go chunk=usage\n" +
+		"fmt.Println(\"usage\")\n" +
+		"```\n\n" +
+		"```go chunk=usage\n" +
+		"os.Exit(2)\n" +
+		"```\n\n" +
+		"```go chunk=main\n" +
+		"\n" +
+		"func run() {}\n" +
+		"```\n\n" +
+		"```bash\n" +
+		"go run .\n" +
+		"```\n"
+
+	codeBlocks, err := New().Extract([]byte(markdown))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	tangled, err := Tangle(codeBlocks)
+	if err != nil {
+		t.Fatalf("Failed to tangle: %v", err)
+	}
+
+	if len(tangled) != 2 {
+		t.Fatalf("Expected the main chunk and the bash block, got %d blocks", len(tangled))
+	}
+	expected := "package main\n\n" +
+		"func main() {\n" +
+		"\targs := os.Args[1:]\n" +
+		"\tif len(args) == 0 {\n" +
+		"\t\tfmt.Println(\"usage\")\n" +
+		"\t\tos.Exit(2)\n" +
+		"\t}\n" +
+		"\trun()\n" +
+		"}\n" +
+		"\n" +
+		"func run() {}\n"
+	if tangled[0].Content != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, tangled[0].Content)
+	}
+	if tangled[0].Attributes.Filename() != "main.go" || tangled[0].Position.StartLine != 3 {
+		t.Errorf("Expected the root chunk to keep its filename and position, got %+v", tangled[0])
+	}
+	if tangled[1].Language != "bash" {
+		t.Errorf("Expected the unrelated block to be kept, got %+v", tangled[1])
+	}
+}
+
+func TestTangleWithoutChunks(t *testing.T) {
+	codeBlocks, err := New().Extract([]byte("```text\n<<not a reference>>\n```\n"))
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	tangled, err := Tangle(codeBlocks)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tangled) != 1 || tangled[0].Content != "<<not a reference>>\n" {
+		t.Errorf("Expected blocks to be untouched, got %+v", tangled)
+	}
+}
+
+func TestTangleErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		message  string
+	}{
+		{
+			"undefined",
+			"```go chunk=a filename=\"a.go\"\npackage a\n<<missing>>\n```\n",
+			"doc.md:3: undefined chunk <<missing>>",
+		},
+		{
+			"cycle",
+			"```go chunk=a filename=\"a.go\"\n<<b>>\n```\n\n```go chunk=b\nx\n  <<a>>\n```\n",
+			"doc.md:7: chunk <<a>> references itself: a > b > a",
+		},
+		{
+			"self reference",
+			"```go chunk=a filename=\"a.go\"\n<<a>>\n```\n",
+			"doc.md:2: chunk <<a>> references itself: a > a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codeBlocks, err := New().Extract([]byte(tt.markdown))
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			for i := range codeBlocks {
+				codeBlocks[i].Position.Path = "doc.md"
+			}
+			_, err = Tangle(codeBlocks)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
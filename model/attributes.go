@@ -0,0 +1,240 @@
+package model
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Attributes holds the structured metadata that follows the language in a fenced code block info string.
+// It covers the common dialects: Docusaurus/VitePress (title="x" {1,4-6} showLineNumbers),
+// Pandoc ({.python #id key=value}), MyST ({code-block} python) and Quarto/knitr ({python echo=false}).
+type Attributes struct {
+	// ID is a Pandoc-style identifier written as #id inside braces.
+	ID string `json:"id,omitempty"`
+	// Classes are Pandoc-style .class names, excluding the one consumed as the language.
+	Classes []string `json:"classes,omitempty"`
+	// Values are key=value pairs with any surrounding quotes removed.
+	Values map[string]string `json:"values,omitempty"`
+	// Flags are bare words such as showLineNumbers.
+	Flags []string `json:"flags,omitempty"`
+	// Lines are brace-delimited line ranges such as {1,4-6}, one entry per range.
+	Lines []string `json:"lines,omitempty"`
+	// Directive is the MyST directive name, e.g. code-block for ```{code-block} python.
+	Directive string `json:"directive,omitempty"`
+}
+
+// mystDirectives are the MyST directive names that introduce a code block; the language follows the directive.
+var mystDirectives = map[string]bool{
+	"code":       true,
+	"code-block": true,
+	"code-cell":  true,
+	"sourcecode": true,
+}
+
+var lineRangePattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// ParseInfo splits a fenced code block info string into the language and its attributes.
+// The language is the first bare word, the first Pandoc class, the word following a MyST directive
+// or the first word inside a Quarto/knitr brace group. It returns an empty language when none is present.
+func ParseInfo(info string) (string, Attributes) {
+	var language string
+	var attributes Attributes
+
+	for i, token := range splitInfo(strings.TrimSpace(info)) {
+		switch {
+		case strings.HasPrefix(token, "{") && strings.HasSuffix(token, "}"):
+			attributes.parseGroup(token[1:len(token)-1], &language)
+		case i == 0 && !strings.Contains(token, "="):
+			language = token
+		default:
+			attributes.parseItem(token, &language, false)
+		}
+	}
+
+	return language, attributes
+}
+
+// Get returns the value of the key=value attribute named key.
+func (a Attributes) Get(key string) (string, bool) {
+	value, found := a.Values[key]
+	return value, found
+}
+
+// Has reports whether the bare flag is present.
+func (a Attributes) Has(flag string) bool {
+	for _, f := range a.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Filename returns the output filename declared on the fence, or an empty string if there is none.
+// A filename= attribute wins over title=; a title is only used when it contains no whitespace,
+// so prose titles such as title="Hello World" are ignored. Docusaurus writes titles relative to
+// the project root with a leading slash, which is removed.
+func (a Attributes) Filename() string {
+	if filename, found := a.Get("filename"); found && filename != "" {
+		return filename
+	}
+	if title, found := a.Get("title"); found && title != "" && !strings.ContainsFunc(title, unicode.IsSpace) {
+		return strings.TrimLeft(title, "/")
+	}
+	return ""
+}
+
+// Mode returns the file permission declared by a mode= attribute, zero if there is none.
+func (a Attributes) Mode() (os.FileMode, error) {
+	text, _ := a.Get("mode")
+	return ParseMode(text)
+}
+
+// parseGroup parses the contents of a brace-delimited group, which is either a list of line ranges
+// or a list of attributes.
+func (a *Attributes) parseGroup(group string, language *string) {
+	items := splitItems(group)
+	if len(items) == 0 {
+		return
+	}
+
+	ranges := true
+	for _, item := range items {
+		if !lineRangePattern.MatchString(item) {
+			ranges = false
+			break
+		}
+	}
+	if ranges {
+		a.Lines = append(a.Lines, items...)
+		return
+	}
+
+	for i, item := range items {
+		a.parseItem(item, language, i == 0)
+	}
+}
+
+// parseItem records a single attribute. Leading is set for the first item of a brace group,
+// where a bare word names a MyST directive or a Quarto/knitr language.
+func (a *Attributes) parseItem(item string, language *string, leading bool) {
+	switch {
+	case strings.HasPrefix(item, "=") && *language == "":
+		// Pandoc raw attribute, e.g. {=html}
+		*language = item[1:]
+	case strings.HasPrefix(item, "#") && len(item) > 1:
+		a.ID = item[1:]
+	case strings.HasPrefix(item, ".") && len(item) > 1:
+		if *language == "" {
+			*language = item[1:]
+		} else {
+			a.Classes = append(a.Classes, item[1:])
+		}
+	case strings.Contains(item, "="):
+		key, value, _ := strings.Cut(item, "=")
+		if a.Values == nil {
+			a.Values = make(map[string]string)
+		}
+		a.Values[key] = unquote(value)
+	case leading && mystDirectives[strings.ToLower(item)]:
+		a.Directive = item
+	case *language == "":
+		*language = item
+	default:
+		a.Flags = append(a.Flags, item)
+	}
+}
+
+// splitInfo splits an info string on whitespace, keeping quoted values and brace groups intact.
+// A brace group directly attached to a word, as in js{4}, becomes a token of its own.
+func splitInfo(info string) []string {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	depth := 0
+	group := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range info {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{':
+			if depth == 0 && !strings.Contains(current.String(), "=") {
+				flush()
+				group = true
+			}
+			depth++
+		case r == '}':
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 && group {
+				current.WriteRune(r)
+				flush()
+				group = false
+				continue
+			}
+		case depth == 0 && unicode.IsSpace(r):
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return tokens
+}
+
+// splitItems splits the contents of a brace group on whitespace and commas outside quotes.
+func splitItems(group string) []string {
+	var items []string
+	var current strings.Builder
+	var quote rune
+
+	for _, r := range group {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',' || unicode.IsSpace(r):
+			if current.Len() > 0 {
+				items = append(items, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		items = append(items, current.String())
+	}
+
+	return items
+}
+
+// unquote removes a matching pair of surrounding single or double quotes.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       string
+		language   string
+		attributes Attributes
+	}{
+		// Plain language tags
+		{"empty", "", "", Attributes{}},
+		{"language only", "go", "go", Attributes{}},
+		{"surrounding whitespace", "  python  ", "python", Attributes{}},
+
+		// Docusaurus / VitePress
+		{
+			"docusaurus title and lines",
+			`go title="main.go" {3-5}`,
+			"go",
+			Attributes{Values: map[string]string{"title": "main.go"}, Lines: []string{"3-5"}},
+		},
+		{
+			"docusaurus full meta",
+			`jsx title="/src/components/Hello.js" {1,4-6,11} showLineNumbers`,
+			"jsx",
+			Attributes{
+				Values: map[string]string{"title": "/src/components/Hello.js"},
+				Flags:  []string{"showLineNumbers"},
+				Lines:  []string{"1", "4-6", "11"},
+			},
+		},
+		{
+			"vitepress attached line range",
+			"js{4}",
+			"js",
+			Attributes{Lines: []string{"4"}},
+		},
+		{
+			"quoted value with spaces",
+			`python title="Hello World.py" filename='app/main.py'`,
+			"python",
+			Attributes{Values: map[string]string{"title": "Hello World.py", "filename": "app/main.py"}},
+		},
+		{
+			"unquoted value",
+			"go filename=cmd/server/main.go",
+			"go",
+			Attributes{Values: map[string]string{"filename": "cmd/server/main.go"}},
+		},
+
+		// Pandoc
+		{
+			"pandoc class as language",
+			`{.haskell .numberLines startFrom="100"}`,
+			"haskell",
+			Attributes{Classes: []string{"numberLines"}, Values: map[string]string{"startFrom": "100"}},
+		},
+		{
+			"pandoc language then attributes",
+			"haskell {#mycode .numberLines}",
+			"haskell",
+			Attributes{ID: "mycode", Classes: []string{"numberLines"}},
+		},
+		{
+			"pandoc raw attribute",
+			"{=html}",
+			"html",
+			Attributes{},
+		},
+
+		// MyST
+		{
+			"myst code-block directive",
+			"{code-block} python",
+			"python",
+			Attributes{Directive: "code-block"},
+		},
+		{
+			"myst code-cell directive",
+			"{code-cell} ipython3",
+			"ipython3",
+			Attributes{Directive: "code-cell"},
+		},
+
+		// Quarto / knitr
+		{
+			"quarto executable cell",
+			"{python}",
+			"python",
+			Attributes{},
+		},
+		{
+			"knitr chunk options",
+			"{r setup, echo=FALSE}",
+			"r",
+			Attributes{Flags: []string{"setup"}, Values: map[string]string{"echo": "FALSE"}},
+		},
+		{
+			"quarto class with filename",
+			`{.python filename="run.py"}`,
+			"python",
+			Attributes{Values: map[string]string{"filename": "run.py"}},
+		},
+
+		// No language
+		{
+			"attributes without language",
+			`title="notes.txt"`,
+			"",
+			Attributes{Values: map[string]string{"title": "notes.txt"}},
+		},
+		{
+			"line ranges without language",
+			"{1,3}",
+			"",
+			Attributes{Lines: []string{"1", "3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language, attributes := ParseInfo(tt.info)
+			if language != tt.language {
+				t.Errorf("ParseInfo(%q) language = %q, want %q", tt.info, language, tt.language)
+			}
+			if !reflect.DeepEqual(attributes, tt.attributes) {
+				t.Errorf("ParseInfo(%q) attributes = %+v, want %+v", tt.info, attributes, tt.attributes)
+			}
+		})
+	}
+}
+
+func TestAttributesAccessors(t *testing.T) {
+	_, attributes := ParseInfo(`go title="main.go" showLineNumbers`)
+
+	if value, found := attributes.Get("title"); !found || value != "main.go" {
+		t.Errorf("Get(title) = %q, %v, want %q, true", value, found, "main.go")
+	}
+	if _, found := attributes.Get("filename"); found {
+		t.Error("Get(filename) should not be found")
+	}
+	if !attributes.Has("showLineNumbers") {
+		t.Error("Has(showLineNumbers) should be true")
+	}
+	if attributes.Has("title") {
+		t.Error("Has(title) should be false for a key=value attribute")
+	}
+}
+
+func TestAttributesFilename(t *testing.T) {
+	tests := []struct {
+		info     string
+		expected string
+	}{
+		{`go filename="cmd/server/main.go"`, "cmd/server/main.go"},
+		{`go title="main.go"`, "main.go"},
+		{`jsx title="/src/components/Hello.js"`, "src/components/Hello.js"},
+		{`go filename="a.go" title="b.go"`, "a.go"},
+		{`go title="Hello World"`, ""},
+		{`go filename=""`, ""},
+		{"go", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.info, func(t *testing.T) {
+			_, attributes := ParseInfo(tt.info)
+			if filename := attributes.Filename(); filename != tt.expected {
+				t.Errorf("Filename() for %q = %q, want %q", tt.info, filename, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAttributesMode(t *testing.T) {
+	tests := []struct {
+		info     string
+		expected os.FileMode
+		valid    bool
+	}{
+		{"sh mode=0755", 0755, true},
+		{`sh mode="600"`, 0600, true},
+		{"sh", 0, true},
+		{"sh mode=rwx", 0, false},
+		{"sh mode=01777", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.info, func(t *testing.T) {
+			_, attributes := ParseInfo(tt.info)
+			mode, err := attributes.Mode()
+			if tt.valid && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("Expected an invalid mode to be rejected")
+			}
+			if mode != tt.expected {
+				t.Errorf("Mode() for %q = %04o, want %04o", tt.info, mode, tt.expected)
+			}
+		})
+	}
+}
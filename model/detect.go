@@ -0,0 +1,217 @@
+package model
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ShebangLanguage returns the language of the interpreter named on a #! first line of content,
+// e.g. bash for #!/bin/bash and python for #!/usr/bin/env python3, or an empty string if there is
+// no shebang or its interpreter is not a known language.
+func ShebangLanguage(content string) string {
+	if !HasShebang(content) {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as -S
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	// python3.12 and node20 name the same languages as python and node
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	if language, found := interpreterLanguages[interpreter]; found {
+		return language
+	}
+	if KnownLanguage(interpreter) {
+		return interpreter
+	}
+	return ""
+}
+
+// interpreterLanguages maps interpreters whose name is not a built-in language.
+var interpreterLanguages = map[string]string{
+	"node":   "javascript",
+	"nodejs": "javascript",
+	"deno":   "typescript",
+	"pwsh":   "powershell",
+	"runghc": "haskell",
+	"ash":    "sh",
+	"dash":   "sh",
+}
+
+// DetectLanguage guesses the language of content offline, for blocks without a usable language.
+// In order of confidence it uses a shebang line, an editor modeline (vim: ft=go, -*- mode: ruby -*-),
+// a well-known first line (package main, <?php, FROM, apiVersion:) and finally statistics of
+// characteristic tokens. It returns the language and a confidence between 0 and 1, or an empty
+// string and 0 if nothing is recognised.
+func DetectLanguage(content string) (string, float64) {
+	if language := ShebangLanguage(content); language != "" {
+		return language, 1
+	}
+	if language := modelineLanguage(content); language != "" {
+		return language, 0.95
+	}
+	if language, confidence := firstLineLanguage(content); language != "" {
+		return language, confidence
+	}
+	return tokenLanguage(content)
+}
+
+// KnownLanguage reports whether language is registered in DefaultLanguages, ignoring case.
+func KnownLanguage(language string) bool {
+	_, found := DefaultLanguages.Lookup(language)
+	return found
+}
+
+var (
+	vimModeline   = regexp.MustCompile(`\b(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax|syn)=([\w+#-]+)`)
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*)?([\w+#-]+)\s*;?.*?-\*-`)
+)
+
+// modeAliases maps editor file type names that are not known languages.
+var modeAliases = map[string]string{
+	"shell-script":    "sh",
+	"javascript-mode": "javascript",
+	"js2":             "javascript",
+	"c++":             "cpp",
+	"cs":              "csharp",
+	"rs":              "rust",
+	"py":              "python",
+	"rb":              "ruby",
+}
+
+// modelineLanguage returns the file type set by a vim or emacs modeline in the first or last five lines.
+func modelineLanguage(content string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	candidates := lines
+	if len(lines) > 10 {
+		candidates = append(append([]string(nil), lines[:5]...), lines[len(lines)-5:]...)
+	}
+	for _, line := range candidates {
+		for _, modeline := range []*regexp.Regexp{vimModeline, emacsModeline} {
+			if match := modeline.FindStringSubmatch(line); match != nil {
+				name := strings.ToLower(match[1])
+				if alias, found := modeAliases[name]; found {
+					return alias
+				}
+				if KnownLanguage(name) {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// firstLineRule recognises a language by the first non-blank line of a block.
+type firstLineRule struct {
+	pattern    *regexp.Regexp
+	language   string
+	confidence float64
+}
+
+var firstLineRules = []firstLineRule{
+	{regexp.MustCompile(`^<\?php\b`), "php", 0.95},
+	{regexp.MustCompile(`^<\?xml\b`), "xml", 0.95},
+	{regexp.MustCompile(`(?i)^(<!doctype html|<html\b)`), "html", 0.95},
+	{regexp.MustCompile(`^apiVersion:\s*\S`), "yaml", 0.95},
+	{regexp.MustCompile(`^syntax\s*=\s*"proto[23]"`), "protobuf", 0.95},
+	{regexp.MustCompile(`^(diff --git |--- a/|Index: )`), "diff", 0.9},
+	{regexp.MustCompile(`^package [a-z][\w.]*;$`), "java", 0.9},
+	{regexp.MustCompile(`^package [a-z]\w*$`), "go", 0.9},
+	{regexp.MustCompile(`^FROM\s+[\w./:@${}-]+(\s+(AS|as)\s+\w+)?$`), "dockerfile", 0.85},
+	{regexp.MustCompile(`^#include\s*<(iostream|vector|string|memory|map)>`), "cpp", 0.85},
+	{regexp.MustCompile(`^#include\s*[<"]`), "c", 0.8},
+	{regexp.MustCompile(`^using System\b`), "csharp", 0.85},
+	{regexp.MustCompile(`^(use (std|crate)::|fn main\(\))`), "rust", 0.85},
+	{regexp.MustCompile(`^(import React\b|'use strict'|"use strict")`), "javascript", 0.8},
+	{regexp.MustCompile(`(?i)^(select\s.+\sfrom\s|insert\s+into\s|create\s+(table|index|view)\s)`), "sql", 0.8},
+	{regexp.MustCompile(`^---\s*$`), "yaml", 0.6},
+}
+
+// firstLineLanguage applies firstLineRules, and recognises JSON documents by parsing them.
+func firstLineLanguage(content string) (string, float64) {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return "", 0
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return "json", 0.9
+	}
+	first, _, _ := strings.Cut(trimmed, "\n")
+	first = strings.TrimSpace(first)
+	for _, rule := range firstLineRules {
+		if rule.pattern.MatchString(first) {
+			return rule.language, rule.confidence
+		}
+	}
+	return "", 0
+}
+
+// languageTokens are patterns characteristic of a language, each counted at most
+// maxTokenMatches times so that one repeated construct cannot dominate.
+var languageTokens = map[string][]*regexp.Regexp{
+	"python": compileAll(`(?m)^\s*def \w+\(.*\):\s*$`, `(?m)^\s*(from [\w.]+ )?import \w+`, `\bself\.`, `(?m)^\s*elif\b`,
+		`(?m)^\s*(if|for|while|with|try|else)\b.*:\s*$`, `\bNone\b`),
+	"go":         compileAll(`\bfunc\b`, `:=`, `\bfmt\.`, `\berr != nil\b`, `(?m)^\s*package \w+$`, `\bchan\b`),
+	"javascript": compileAll(`\bconst \w+ =`, `=>`, `\bfunction\b`, `console\.log`, `\brequire\(`, `===`),
+	"typescript": compileAll(`:\s*(string|number|boolean)\b`, `\binterface \w+\s*\{`, `\bexport (type|interface)\b`),
+	"rust":       compileAll(`\bfn \w+`, `\blet mut\b`, `\bimpl\b`, `\w+!\(`, `&(mut )?(str|self)\b`, `\bpub fn\b`),
+	"ruby":       compileAll(`(?m)^\s*end\s*$`, `\bputs\b`, `\bdo \|\w+(, \w+)*\|`, `\battr_(accessor|reader)\b`, `(?m)^\s*require ['"]`),
+	"bash":       compileAll(`(?m)^\s*echo\b`, `\$\{?\w+\}?`, `(?m)^\s*(fi|done|esac)\s*$`, `(?m)^\s*(if|elif) \[`, `(?m)^\s*export \w+=`, `\s&&\s`),
+	"java":       compileAll(`\bpublic (static )?(final )?(class|void|int|String)\b`, `System\.out\.`, `\bprivate\b`, `\bnew \w+\(`, `@Override`),
+	"c":          compileAll(`#include\s*<`, `\bprintf\(`, `\bint main\(`, `\bmalloc\(`, `\bsizeof\b`),
+	"sql":        compileAll(`(?i)\bselect\b[\s\S]+?\bfrom\b`, `(?i)\bwhere\b`, `(?i)\binsert into\b`, `(?i)\bcreate table\b`, `(?i)\bjoin\b`),
+	"yaml":       compileAll(`(?m)^\s*[\w-]+:\s+\S`, `(?m)^\s*- [\w"']`, `(?m)^\s*[\w-]+:\s*$`),
+	"html":       compileAll(`</\w+>`, `<(div|span|p|a|head|body)\b`),
+	"css":        compileAll(`(?m)^\s*[.#]?[\w-]+(\s*[.#:>]?[\w-]+)*\s*\{`, `(?m)^\s*[\w-]+:\s*[^;]+;\s*$`),
+}
+
+const maxTokenMatches = 5
+
+func compileAll(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// tokenLanguage scores every language by its characteristic tokens. The confidence grows with the
+// score of the best language and shrinks with how close the runner-up comes, and never exceeds 0.75.
+func tokenLanguage(content string) (string, float64) {
+	best, bestScore, secondScore := "", 0, 0
+	// Languages are scored in name order, so that a tie goes to the first name and counts as the runner-up
+	for _, language := range slices.Sorted(maps.Keys(languageTokens)) {
+		score := 0
+		for _, pattern := range languageTokens[language] {
+			score += len(pattern.FindAllStringIndex(content, maxTokenMatches))
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, secondScore = language, score, bestScore
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+	if bestScore < 3 {
+		return "", 0
+	}
+	margin := float64(bestScore-secondScore) / float64(bestScore)
+	return best, math.Min(0.75, 0.25+0.05*float64(bestScore)) * (0.5 + 0.5*margin)
+}
@@ -0,0 +1,68 @@
+package model
+
+import "testing"
+
+func TestShebangLanguage(t *testing.T) {
+	tests := map[string]string{
+		"#!/bin/bash\necho hi\n":                 "bash",
+		"#!/bin/sh\n":                            "sh",
+		"#!/usr/bin/env python3\nprint(1)\n":     "python",
+		"#!/usr/bin/env -S node --no-warnings\n": "javascript",
+		"#!/usr/local/bin/ruby -w\n":             "ruby",
+		"#! /usr/bin/perl\n":                     "perl",
+		"#!/opt/custom/tool\n":                   "",
+		"echo no shebang\n":                      "",
+		"#!":                                     "",
+	}
+	for content, expected := range tests {
+		if language := ShebangLanguage(content); language != expected {
+			t.Errorf("ShebangLanguage(%q) = %q, want %q", content, language, expected)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		content       string
+		language      string
+		minConfidence float64
+	}{
+		{"#!/usr/bin/env python3\nprint(1)\n", "python", 1},
+		{"x = 1\n# vim: set ft=ruby ts=2:\n", "ruby", 0.9},
+		{"# -*- mode: shell-script -*-\nls\n", "sh", 0.9},
+		{"package main\n\nfunc main() {}\n", "go", 0.85},
+		{"package com.example;\n\nclass A {}\n", "java", 0.85},
+		{"<?php\necho 'hi';\n", "php", 0.9},
+		{"FROM golang:1.25 AS build\nRUN go build\n", "dockerfile", 0.8},
+		{"apiVersion: v1\nkind: Pod\n", "yaml", 0.9},
+		{"{\"name\": \"codeblocks\", \"tags\": [1, 2]}\n", "json", 0.9},
+		{"import os\n\ndef main():\n    if os.environ:\n        print(self.x)\n    elif True:\n        return None\n", "python", 0.5},
+		{"const add = (a, b) => a + b;\nconsole.log(add(1, 2) === 3);\nfunction f() {}\n", "javascript", 0.5},
+	}
+	for _, test := range tests {
+		language, confidence := DetectLanguage(test.content)
+		if language != test.language || confidence < test.minConfidence {
+			t.Errorf("DetectLanguage(%q) = %q, %.2f, want %q with at least %.2f", test.content, language, confidence, test.language, test.minConfidence)
+		}
+	}
+
+	for _, content := range []string{"", "Hello world\n", "x\ny\n"} {
+		if language, confidence := DetectLanguage(content); language != "" || confidence != 0 {
+			t.Errorf("DetectLanguage(%q) = %q, %.2f, want no guess", content, language, confidence)
+		}
+	}
+	if _, confidence := DetectLanguage("echo $HOME\nexport A=1\n"); confidence > 0.75 {
+		t.Errorf("Expected token statistics to stay below a first line match, got %.2f", confidence)
+	}
+}
+
+func TestDetectLanguageTie(t *testing.T) {
+	// Three Go := and three shell $variables: neither language wins, whatever the map order
+	content := "x := $a\ny := $b\nz := $c\n"
+	for range 50 {
+		language, confidence := DetectLanguage(content)
+		if language != "bash" || confidence != 0.2 {
+			t.Fatalf("DetectLanguage(%q) = %q, %.3f, want bash with 0.2 for a tie", content, language, confidence)
+		}
+	}
+}
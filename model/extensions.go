@@ -1,105 +1,125 @@
 package model
 
-import "strings"
+// LanguageInfo describes a language that code blocks can be tagged with.
+type LanguageInfo struct {
+	Name         string    // canonical name, e.g. go
+	Aliases      []string  // other names used in info strings, e.g. golang
+	Extensions   []string  // file extensions without a dot, the first one for new files
+	Filenames    []string  // well-known whole filenames, e.g. Dockerfile; see WholeFilename
+	LineComment  string    // starts a comment that runs to the end of the line, e.g. //
+	BlockComment [2]string // start and end of a block comment, e.g. /* and */
+	MIMEType     string    // e.g. text/x-go
+}
+
+// Extension returns the extension of new files in the language, the first of Extensions, or an
+// empty string for a language known only by whole filenames such as Dockerfile.
+func (l LanguageInfo) Extension() string {
+	if len(l.Extensions) > 0 {
+		return l.Extensions[0]
+	}
+	return ""
+}
 
-// languageExtensionMap maps common programming language identifiers to file extensions.
-// This is defined at package level for efficiency and reuse across calls.
-var languageExtensionMap = map[string]string{
+// WholeFilename returns the filename that new files in the language are given in place of a name
+// with an extension, e.g. Dockerfile. Only languages without Extensions have one.
+func (l LanguageInfo) WholeFilename() (string, bool) {
+	if len(l.Extensions) > 0 || len(l.Filenames) == 0 {
+		return "", false
+	}
+	return l.Filenames[0], true
+}
+
+// builtinLanguages are the languages every LanguageRegistry from NewLanguageRegistry starts with.
+var builtinLanguages = []LanguageInfo{
 	// Compiled languages
-	"go":      "go",
-	"golang":  "go",
-	"rust":    "rs",
-	"c":       "c",
-	"cpp":     "cpp",
-	"c++":     "cpp",
-	"java":    "java",
-	"kotlin":  "kt",
-	"swift":   "swift",
-	"csharp":  "cs",
-	"c#":      "cs",
-	"objc":    "m",
-	"haskell": "hs",
-	"scala":   "scala",
+	{Name: "go", Aliases: []string{"golang"}, Extensions: []string{"go"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-go"},
+	{Name: "rust", Extensions: []string{"rs"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-rust"},
+	{Name: "c", Extensions: []string{"c", "h"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-c"},
+	{Name: "cpp", Aliases: []string{"c++"}, Extensions: []string{"cpp", "cc", "hpp"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-c++"},
+	{Name: "java", Extensions: []string{"java"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-java"},
+	{Name: "kotlin", Extensions: []string{"kt", "kts"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-kotlin"},
+	{Name: "swift", Extensions: []string{"swift"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-swift"},
+	{Name: "csharp", Aliases: []string{"c#"}, Extensions: []string{"cs"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-csharp"},
+	{Name: "objc", Extensions: []string{"m"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-objectivec"},
+	{Name: "haskell", Extensions: []string{"hs"}, LineComment: "--", BlockComment: [2]string{"{-", "-}"}, MIMEType: "text/x-haskell"},
+	{Name: "scala", Extensions: []string{"scala"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-scala"},
 
 	// Scripting languages
-	"python":  "py",
-	"python3": "py",
-	"ruby":    "rb",
-	"perl":    "pl",
-	"php":     "php",
-	"lua":     "lua",
-	"r":       "R",
-	"julia":   "jl",
+	{Name: "python", Aliases: []string{"python3"}, Extensions: []string{"py"}, LineComment: "#", MIMEType: "text/x-python"},
+	{Name: "ruby", Extensions: []string{"rb"}, LineComment: "#", BlockComment: [2]string{"=begin", "=end"}, MIMEType: "text/x-ruby"},
+	{Name: "perl", Extensions: []string{"pl", "pm"}, LineComment: "#", MIMEType: "text/x-perl"},
+	{Name: "php", Extensions: []string{"php"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "application/x-httpd-php"},
+	{Name: "lua", Extensions: []string{"lua"}, LineComment: "--", BlockComment: [2]string{"--[[", "]]"}, MIMEType: "text/x-lua"},
+	{Name: "r", Extensions: []string{"R"}, LineComment: "#", MIMEType: "text/x-r"},
+	{Name: "julia", Extensions: []string{"jl"}, LineComment: "#", BlockComment: [2]string{"#=", "=#"}, MIMEType: "text/x-julia"},
+	{Name: "groovy", Extensions: []string{"groovy"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-groovy"},
 
 	// Web languages
-	"javascript": "js",
-	"js":         "js",
-	"typescript": "ts",
-	"ts":         "ts",
-	"html":       "html",
-	"css":        "css",
-	"scss":       "scss",
-	"sass":       "sass",
-	"less":       "less",
-	"jsx":        "jsx",
-	"tsx":        "tsx",
-	"vue":        "vue",
-	"svelte":     "svelte",
+	{Name: "javascript", Aliases: []string{"js"}, Extensions: []string{"js", "mjs", "cjs"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/javascript"},
+	{Name: "typescript", Aliases: []string{"ts"}, Extensions: []string{"ts"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "application/typescript"},
+	{Name: "html", Extensions: []string{"html", "htm"}, BlockComment: [2]string{"<!--", "-->"}, MIMEType: "text/html"},
+	{Name: "css", Extensions: []string{"css"}, BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/css"},
+	{Name: "scss", Extensions: []string{"scss"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-scss"},
+	{Name: "sass", Extensions: []string{"sass"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-sass"},
+	{Name: "less", Extensions: []string{"less"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-less"},
+	{Name: "jsx", Extensions: []string{"jsx"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/jsx"},
+	{Name: "tsx", Extensions: []string{"tsx"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/tsx"},
+	{Name: "vue", Extensions: []string{"vue"}, BlockComment: [2]string{"<!--", "-->"}, MIMEType: "text/x-vue"},
+	{Name: "svelte", Extensions: []string{"svelte"}, BlockComment: [2]string{"<!--", "-->"}, MIMEType: "text/x-svelte"},
 
 	// Shell
-	"bash":       "sh",
-	"sh":         "sh",
-	"shell":      "sh",
-	"zsh":        "sh",
-	"fish":       "fish",
-	"powershell": "ps1",
-	"ps1":        "ps1",
+	{Name: "bash", Aliases: []string{"sh", "shell", "zsh"}, Extensions: []string{"sh", "bash", "zsh"}, LineComment: "#", MIMEType: "application/x-sh"},
+	{Name: "fish", Extensions: []string{"fish"}, LineComment: "#", MIMEType: "application/x-fish"},
+	{Name: "powershell", Aliases: []string{"ps1"}, Extensions: []string{"ps1"}, LineComment: "#", BlockComment: [2]string{"<#", "#>"}, MIMEType: "application/x-powershell"},
 
 	// Data formats
-	"json":       "json",
-	"yaml":       "yaml",
-	"yml":        "yaml",
-	"toml":       "toml",
-	"xml":        "xml",
-	"ini":        "ini",
-	"properties": "properties",
+	{Name: "json", Extensions: []string{"json"}, MIMEType: "application/json"},
+	{Name: "yaml", Aliases: []string{"yml"}, Extensions: []string{"yaml", "yml"}, LineComment: "#", MIMEType: "application/yaml"},
+	{Name: "toml", Extensions: []string{"toml"}, LineComment: "#", MIMEType: "application/toml"},
+	{Name: "xml", Extensions: []string{"xml"}, BlockComment: [2]string{"<!--", "-->"}, MIMEType: "application/xml"},
+	{Name: "ini", Extensions: []string{"ini"}, LineComment: ";", MIMEType: "text/x-ini"},
+	{Name: "properties", Extensions: []string{"properties"}, LineComment: "#", MIMEType: "text/x-java-properties"},
 
 	// Markup
-	"markdown": "md",
-	"md":       "md",
-	"tex":      "tex",
-	"latex":    "tex",
+	{Name: "markdown", Aliases: []string{"md"}, Extensions: []string{"md", "markdown"}, BlockComment: [2]string{"<!--", "-->"}, MIMEType: "text/markdown"},
+	{Name: "tex", Aliases: []string{"latex"}, Extensions: []string{"tex"}, LineComment: "%", MIMEType: "application/x-tex"},
 
 	// Database
-	"sql":        "sql",
-	"postgres":   "sql",
-	"postgresql": "sql",
-	"mysql":      "sql",
-	"sqlite":     "sql",
-	"plsql":      "sql",
-	"tsql":       "sql",
+	{Name: "sql", Aliases: []string{"postgres", "postgresql", "mysql", "sqlite", "plsql", "tsql"}, Extensions: []string{"sql"}, LineComment: "--", BlockComment: [2]string{"/*", "*/"}, MIMEType: "application/sql"},
+
+	// Build files, known by whole filename
+	{Name: "dockerfile", Aliases: []string{"docker"}, Filenames: []string{"Dockerfile"}, LineComment: "#", MIMEType: "text/x-dockerfile"},
+	{Name: "makefile", Aliases: []string{"make"}, Filenames: []string{"Makefile", "GNUmakefile"}, LineComment: "#", MIMEType: "text/x-makefile"},
+	{Name: "jenkinsfile", Aliases: []string{"jenkins"}, Filenames: []string{"Jenkinsfile"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-groovy"},
 
 	// Other
-	"dockerfile": "Dockerfile",
-	"docker":     "Dockerfile",
-	"makefile":   "Makefile",
-	"make":       "Makefile",
-	"graphql":    "graphql",
-	"protobuf":   "proto",
-	"proto":      "proto",
-	"diff":       "diff",
-	"patch":      "patch",
+	{Name: "graphql", Extensions: []string{"graphql", "gql"}, LineComment: "#", MIMEType: "application/graphql"},
+	{Name: "protobuf", Aliases: []string{"proto"}, Extensions: []string{"proto"}, LineComment: "//", BlockComment: [2]string{"/*", "*/"}, MIMEType: "text/x-protobuf"},
+	{Name: "diff", Extensions: []string{"diff"}, MIMEType: "text/x-diff"},
+	{Name: "patch", Extensions: []string{"patch"}, MIMEType: "text/x-diff"},
 }
 
 // LanguageToExtension maps common programming language identifiers to file extensions.
-// It performs case-insensitive matching and returns "txt" for unknown languages.
+// It performs case-insensitive matching against DefaultLanguages and returns "txt" for unknown languages.
+// For compatibility, languages known by a whole filename return it, e.g. Dockerfile for dockerfile;
+// see LanguageInfo.WholeFilename to tell them apart.
 func LanguageToExtension(language string) string {
-	// Convert to lowercase for case-insensitive matching
-	ext, found := languageExtensionMap[strings.ToLower(language)]
-	if found {
-		return ext
+	if info, found := LookupLanguage(language); found {
+		if filename, whole := info.WholeFilename(); whole {
+			return filename
+		}
 	}
+	return DefaultLanguages.Extension(language)
+}
+
+// LookupLanguage returns the description of language in DefaultLanguages, ignoring case.
+func LookupLanguage(language string) (LanguageInfo, bool) {
+	return DefaultLanguages.Info(language)
+}
 
-	// Fallback: use txt for unknown languages
-	return "txt"
+// LanguageMatches reports whether language selects the same language as selector, honouring the
+// aliases in DefaultLanguages: golang matches go, and shell matches bash, sh and zsh.
+// Known languages are compared by their extension, unknown ones by case-insensitive name.
+func LanguageMatches(language, selector string) bool {
+	return DefaultLanguages.Matches(language, selector)
 }
@@ -114,3 +114,33 @@ func TestLanguageToExtensionConsistency(t *testing.T) {
 		}
 	}
 }
+
+func TestLanguageMatches(t *testing.T) {
+	tests := []struct {
+		language string
+		selector string
+		expected bool
+	}{
+		{"go", "go", true},
+		{"go", "golang", true},
+		{"Golang", "GO", true},
+		{"bash", "shell", true},
+		{"zsh", "shell", true},
+		{"sh", "bash", true},
+		{"yml", "yaml", true},
+		{"python", "go", false},
+		{"fish", "shell", false},
+		{"mylang", "MyLang", true},  // Unknown languages compare by name
+		{"mylang", "otherlang", false},
+		{"unknown", "txt", false},   // Unknown languages never match through the txt fallback
+		{"", "go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language+"/"+tt.selector, func(t *testing.T) {
+			if matched := LanguageMatches(tt.language, tt.selector); matched != tt.expected {
+				t.Errorf("LanguageMatches(%q, %q) = %v, want %v", tt.language, tt.selector, matched, tt.expected)
+			}
+		})
+	}
+}
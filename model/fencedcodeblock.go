@@ -1,18 +1,37 @@
 package model
 
 type FencedCodeBlock struct {
-	Language string
-	Content  string
+	Language   string
+	Attributes Attributes
+	Content    string
+	Position   Position
+	Fence      Fence
+	// Headings are the enclosing Markdown headings, outermost first.
+	Headings []Heading
+	// Indented is set for indented (non-fenced) code blocks, which have no fence or info string.
+	Indented bool
 }
 
+// ToSourceCode names the block with filenameGenerator. The file mode comes from a mode= attribute;
+// an invalid one is ignored here, callers that want to report it should check Attributes.Mode.
 func (b FencedCodeBlock) ToSourceCode(filenameGenerator func(block FencedCodeBlock) string) SourceCode {
+	mode, _ := b.Attributes.Mode()
 	return SourceCode{
 		Filename: filenameGenerator(b),
 		Language: b.Language,
 		Content:  b.Content,
+		Mode:     mode,
 	}
 }
 
+// Heading returns the innermost enclosing heading and false if the block is not under any heading.
+func (b FencedCodeBlock) Heading() (Heading, bool) {
+	if len(b.Headings) == 0 {
+		return Heading{}, false
+	}
+	return b.Headings[len(b.Headings)-1], true
+}
+
 func (b FencedCodeBlock) String() string {
 	return b.Content
 }
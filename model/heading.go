@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Heading is a Markdown heading that encloses a code block.
+type Heading struct {
+	Text  string `json:"text"`
+	Level int    `json:"level"`
+}
+
+// Slug returns the heading text in the lowercase, hyphen-separated form used for anchors.
+func (h Heading) Slug() string {
+	return Slugify(h.Text)
+}
+
+// Slugify lowercases s, keeps letters and digits, and collapses every other run of characters
+// into a single hyphen, e.g. "Usage & Examples" becomes "usage-examples".
+func Slugify(s string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Usage", "usage"},
+		{"Getting Started", "getting-started"},
+		{"Usage & Examples", "usage-examples"},
+		{"  API: v2.0  ", "api-v2-0"},
+		{"Café Menü", "café-menü"},
+		{"", ""},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if slug := Slugify(tt.text); slug != tt.expected {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.text, slug, tt.expected)
+			}
+		})
+	}
+}
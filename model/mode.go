@@ -0,0 +1,34 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultFileMode is the permission of files written by a Writer without a FileMode.
+const DefaultFileMode os.FileMode = 0644
+
+// ParseMode parses an octal permission such as 0755 or 644. An empty string returns zero.
+func ParseMode(text string) (os.FileMode, error) {
+	if text == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal permission such as 0755", text)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Executable adds the execute bit for everyone who may read a file with the given mode,
+// so 0644 becomes 0755 and 0600 becomes 0700.
+func Executable(mode os.FileMode) os.FileMode {
+	return mode | (mode&0444)>>2
+}
+
+// HasShebang reports whether content starts with a #! interpreter line.
+func HasShebang(content string) bool {
+	return strings.HasPrefix(content, "#!")
+}
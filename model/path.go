@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePath returns the location of filename inside root. Filename must be a slash-separated
+// relative path that stays inside root: absolute paths, .. escapes and existing symlinked
+// directories that lead outside root are rejected, so a hostile Markdown file cannot write
+// anywhere else through a fence attribute or name template.
+func ResolvePath(root, filename string) (string, error) {
+	local := filepath.FromSlash(filename)
+	if filename == "" || path.IsAbs(filename) || filepath.IsAbs(local) || filepath.VolumeName(local) != "" {
+		return "", fmt.Errorf("%q is not a relative path", filename)
+	}
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%q escapes the output directory", filename)
+	}
+	destination := filepath.Join(root, local)
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if os.IsNotExist(err) {
+		// Nothing exists below a missing root, so there are no symlinks to follow
+		return destination, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// The file itself is replaced by a rename, which does not follow symlinks, but every
+	// existing directory on the way to it must stay inside the root
+	current := root
+	for _, part := range strings.Split(filepath.Dir(local), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			return "", fmt.Errorf("%q goes through the unresolvable symlink %s: %w", filename, current, err)
+		}
+		if rel, err := filepath.Rel(realRoot, target); err != nil || !(rel == "." || filepath.IsLocal(rel)) {
+			return "", fmt.Errorf("%q leaves the output directory through the symlink %s", filename, current)
+		}
+	}
+
+	return destination, nil
+}
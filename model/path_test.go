@@ -0,0 +1,78 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "inside", "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "inside"), filepath.Join(root, "alias")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		filename string
+		valid    bool
+	}{
+		{"main.go", true},
+		{"cmd/server/main.go", true},
+		{"inside/nested/main.go", true},
+		{"a/../main.go", true},
+		{"alias/main.go", true}, // Symlink staying inside the root
+		{"escape", true},        // The file itself is replaced, not written through
+		{"", false},
+		{"/etc/passwd", false},
+		{"../main.go", false},
+		{"../../.ssh/authorized_keys", false},
+		{"a/../../main.go", false},
+		{"escape/main.go", false}, // Symlink leaving the root
+		{"escape/a/b.go", false},
+		{"dangling/main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			destination, err := ResolvePath(root, tt.filename)
+			if tt.valid {
+				if err != nil {
+					t.Errorf("Expected %q to be accepted, got %v", tt.filename, err)
+				} else if destination != filepath.Join(root, filepath.FromSlash(tt.filename)) {
+					t.Errorf("Unexpected destination %s", destination)
+				}
+			} else if err == nil {
+				t.Errorf("Expected %q to be rejected", tt.filename)
+			}
+		})
+	}
+
+	// A root that does not exist yet is checked lexically
+	if _, err := ResolvePath(filepath.Join(root, "new"), "main.go"); err != nil {
+		t.Errorf("Expected a missing root to be accepted, got %v", err)
+	}
+	if _, err := ResolvePath(filepath.Join(root, "new"), "../main.go"); err == nil {
+		t.Error("Expected an escape from a missing root to be rejected")
+	}
+}
+
+func TestSourceCodeSaveRejectsEscapes(t *testing.T) {
+	root := t.TempDir()
+	sourceCode := SourceCode{Filename: "../escaped.txt", Content: "x"}
+	if err := sourceCode.Save(filepath.Join(root, "out")); err == nil {
+		t.Fatal("Expected Save to reject a path outside the directory")
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no file to be written outside the directory")
+	}
+}
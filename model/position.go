@@ -0,0 +1,40 @@
+package model
+
+import "fmt"
+
+// Position locates a code block in its source document. Lines and columns are 1-based;
+// columns and offsets count bytes.
+type Position struct {
+	// Path is the input document, empty for standard input.
+	Path string `json:"path"`
+	// StartLine and StartColumn locate the first character of the opening fence.
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	// EndLine and EndColumn locate the last character of the closing fence, or the end of the
+	// last content line if the block is not closed.
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+	// StartOffset and EndOffset delimit the block from the opening fence to the end of the closing fence.
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+}
+
+// String formats the position as path:line:column, using <stdin> for standard input.
+func (p Position) String() string {
+	path := p.Path
+	if path == "" {
+		path = "<stdin>"
+	}
+	return fmt.Sprintf("%s:%d:%d", path, p.StartLine, p.StartColumn)
+}
+
+// Fence describes the opening fence of a code block.
+type Fence struct {
+	// Char is the fence character, '`' or '~'.
+	Char byte
+	// Length is the number of fence characters, at least 3.
+	Length int
+	// Indent is the number of bytes before the fence on its line, including any container
+	// prefixes such as list indentation or blockquote markers.
+	Indent int
+}
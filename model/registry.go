@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LanguageRegistry maps language identifiers, such as go, golang or terraform, to descriptions of
+// the languages. Identifiers are case-insensitive. The zero value is an empty registry ready to
+// use, and a registry is safe for concurrent use.
+type LanguageRegistry struct {
+	mu        sync.RWMutex
+	languages map[string]LanguageInfo // by lower-case name and alias
+}
+
+// DefaultLanguages is the registry used by LanguageToExtension, LanguageMatches, LookupLanguage and
+// KnownLanguage. It starts with the built-in languages; register a language on it to make it known
+// everywhere.
+var DefaultLanguages = NewLanguageRegistry()
+
+// NewLanguageRegistry returns a registry holding the built-in languages.
+func NewLanguageRegistry() *LanguageRegistry {
+	r := &LanguageRegistry{}
+	for _, info := range builtinLanguages {
+		if err := r.Add(info); err != nil {
+			panic(err) // the built-in languages are valid
+		}
+	}
+	return r
+}
+
+// Add registers info under its name and aliases, replacing any language registered under them.
+func (r *LanguageRegistry) Add(info LanguageInfo) error {
+	if strings.TrimSpace(info.Name) == "" {
+		return fmt.Errorf("empty language name")
+	}
+	info = info.clone()
+	for i, extension := range info.Extensions {
+		info.Extensions[i] = strings.TrimPrefix(strings.TrimSpace(extension), ".")
+		if info.Extensions[i] == "" || strings.ContainsAny(info.Extensions[i], `/\`) {
+			return fmt.Errorf("invalid extension %q for language %s", extension, info.Name)
+		}
+	}
+	for _, filename := range info.Filenames {
+		if filename == "" || strings.ContainsAny(filename, `/\`) {
+			return fmt.Errorf("invalid filename %q for language %s", filename, info.Name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.languages == nil {
+		r.languages = make(map[string]LanguageInfo)
+	}
+	for _, name := range append([]string{info.Name}, info.Aliases...) {
+		r.languages[strings.ToLower(strings.TrimSpace(name))] = info
+	}
+	return nil
+}
+
+// Register maps language to extension, adding a language or overriding a registered one. An
+// overridden language changes under its name and all its aliases, so registering yaml or yml as yml
+// affects both; it keeps its aliases, comment syntax and MIME type but loses its other extensions
+// and filenames. A leading dot on extension is ignored, so tf and .tf are the same extension.
+func (r *LanguageRegistry) Register(language, extension string) error {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return fmt.Errorf("empty language name")
+	}
+	info, found := r.Info(language)
+	if !found {
+		info = LanguageInfo{Name: language}
+	}
+	info.Extensions, info.Filenames = []string{extension}, nil
+	return r.Add(info)
+}
+
+// Info returns the description of language, ignoring case, and whether the language is registered.
+func (r *LanguageRegistry) Info(language string) (LanguageInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	info, found := r.languages[strings.ToLower(language)]
+	return info.clone(), found
+}
+
+// Lookup returns the extension of language, as LanguageInfo.Extension reports it, ignoring case,
+// and whether the language is registered. The extension is empty for languages known only by
+// whole filenames.
+func (r *LanguageRegistry) Lookup(language string) (string, bool) {
+	info, found := r.Info(language)
+	if !found {
+		return "", false
+	}
+	return info.Extension(), true
+}
+
+// Extension returns the extension of language, or "txt" if it is not registered or has no extension.
+func (r *LanguageRegistry) Extension(language string) string {
+	if extension, _ := r.Lookup(language); extension != "" {
+		return extension
+	}
+	return "txt"
+}
+
+// Matches reports whether language selects the same language as selector: they are equal ignoring
+// case, or both are registered with the same extension, or the same whole filename, so golang
+// matches go.
+func (r *LanguageRegistry) Matches(language, selector string) bool {
+	if strings.EqualFold(language, selector) {
+		return true
+	}
+	info, found := r.Info(language)
+	if !found {
+		return false
+	}
+	selectorInfo, found := r.Info(selector)
+	return found && info.key() == selectorInfo.key()
+}
+
+// key identifies the files of a language: its extension, or else its whole filename.
+func (l LanguageInfo) key() string {
+	if filename, whole := l.WholeFilename(); whole {
+		return filename
+	}
+	return l.Extension()
+}
+
+// clone returns a copy of l that shares no slices with it.
+func (l LanguageInfo) clone() LanguageInfo {
+	l.Aliases = append([]string(nil), l.Aliases...)
+	l.Extensions = append([]string(nil), l.Extensions...)
+	l.Filenames = append([]string(nil), l.Filenames...)
+	return l
+}
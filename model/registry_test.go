@@ -0,0 +1,99 @@
+package model
+
+import "testing"
+
+func TestLanguageRegistry(t *testing.T) {
+	registry := NewLanguageRegistry()
+	if extension, found := registry.Lookup("Golang"); !found || extension != "go" {
+		t.Errorf("Expected the built-in golang alias, got %q, %v", extension, found)
+	}
+	if _, found := registry.Lookup("terraform"); found {
+		t.Fatal("Expected terraform to be unknown before registration")
+	}
+
+	for language, extension := range map[string]string{"Terraform": ".tf", "hcl": "hcl", "ruby": "rbx"} {
+		if err := registry.Register(language, extension); err != nil {
+			t.Fatalf("Register(%q, %q): %v", language, extension, err)
+		}
+	}
+	if extension := registry.Extension("terraform"); extension != "tf" {
+		t.Errorf("Expected a registered language without its dot, got %q", extension)
+	}
+	if extension := registry.Extension("ruby"); extension != "rbx" {
+		t.Errorf("Expected an override of a built-in language, got %q", extension)
+	}
+	if extension := registry.Extension("zig"); extension != "txt" {
+		t.Errorf("Expected txt for an unknown language, got %q", extension)
+	}
+	if !registry.Matches("TERRAFORM", "terraform") || registry.Matches("terraform", "hcl") {
+		t.Error("Expected languages to match by name and extension only")
+	}
+	if DefaultLanguages.Extension("terraform") != "txt" {
+		t.Error("Expected registering on one registry to leave DefaultLanguages alone")
+	}
+
+	for language, extension := range map[string]string{"": "x", "zig": "", "evil": "../x"} {
+		if err := registry.Register(language, extension); err == nil {
+			t.Errorf("Expected Register(%q, %q) to fail", language, extension)
+		}
+	}
+
+	var empty LanguageRegistry
+	if err := empty.Register("zig", "zig"); err != nil || empty.Extension("zig") != "zig" || empty.Extension("go") != "txt" {
+		t.Error("Expected the zero registry to start empty and accept registrations")
+	}
+}
+
+func TestLanguageInfo(t *testing.T) {
+	info, found := LookupLanguage("Golang")
+	if !found || info.Name != "go" || info.LineComment != "//" || info.BlockComment != [2]string{"/*", "*/"} || info.MIMEType != "text/x-go" {
+		t.Errorf("Unexpected description of golang: %+v", info)
+	}
+	if _, whole := info.WholeFilename(); whole {
+		t.Error("Expected go to have no whole filename")
+	}
+
+	for language, expected := range map[string]string{"dockerfile": "Dockerfile", "make": "Makefile", "jenkins": "Jenkinsfile"} {
+		info, found := LookupLanguage(language)
+		filename, whole := info.WholeFilename()
+		if !found || !whole || filename != expected || info.Extension() != "" {
+			t.Errorf("Expected %s to be named %s without an extension, got %+v", language, expected, info)
+		}
+	}
+	if LanguageToExtension("docker") != "Dockerfile" || DefaultLanguages.Extension("docker") != "txt" {
+		t.Error("Expected only LanguageToExtension to report the whole filename")
+	}
+	if !LanguageMatches("docker", "dockerfile") || LanguageMatches("dockerfile", "makefile") {
+		t.Error("Expected whole-filename languages to match by filename")
+	}
+
+	registry := NewLanguageRegistry()
+	terraform := LanguageInfo{Name: "terraform", Aliases: []string{"tf"}, Extensions: []string{".tf", "tfvars"}, LineComment: "#"}
+	if err := registry.Add(terraform); err != nil {
+		t.Fatal(err)
+	}
+	info, found = registry.Info("TF")
+	if !found || info.Name != "terraform" || info.Extension() != "tf" || !registry.Matches("tf", "terraform") {
+		t.Errorf("Expected terraform to be registered with its alias, got %+v", info)
+	}
+	info.Extensions[0] = "changed"
+	if registry.Extension("terraform") != "tf" {
+		t.Error("Expected Info to return a copy")
+	}
+
+	if err := registry.Register("python", "pyw"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := registry.Info("python3"); info.Extension() != "pyw" || info.LineComment != "#" || len(info.Aliases) != 1 {
+		t.Errorf("Expected Register to override the language under its aliases and keep its metadata, got %+v", info)
+	}
+	if err := registry.Register("yml", "yml"); err != nil {
+		t.Fatal(err)
+	}
+	if registry.Extension("yaml") != "yml" || !registry.Matches("yml", "yaml") {
+		t.Error("Expected registering an alias to override the whole language")
+	}
+	if err := registry.Add(LanguageInfo{Name: "bad", Filenames: []string{"dir/Bad"}}); err == nil {
+		t.Error("Expected a filename with a directory to be rejected")
+	}
+}
@@ -1,20 +1,21 @@
 package model
 
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-)
+import "os"
 
 type SourceCode struct {
 	Filename string
 	Language string
 	Content  string
+	// Mode is the permission of the written file. When zero the Writer decides, see Writer.Write.
+	Mode os.FileMode
 }
 
+// Save writes the source code to Filename relative to directory, creating any missing
+// directories and leaving an existing file with the same content untouched. It is shorthand
+// for a Writer with default settings, see Writer.Write.
 func (c SourceCode) Save(directory string) error {
-	fmt.Fprintf(os.Stderr, "Saving file: %s in %s", c.Filename, directory)
-	return os.WriteFile(filepath.Join(directory, c.Filename), []byte(c.Content), 0644)
+	_, err := Writer{Root: directory}.Write(c)
+	return err
 }
 
 func (c SourceCode) String() string {
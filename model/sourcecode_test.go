@@ -0,0 +1,48 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceCodeSave(t *testing.T) {
+	dir := t.TempDir()
+
+	sourceCode := SourceCode{Filename: "pkg/main.go", Language: "go", Content: "package main\n"}
+	if err := sourceCode.Save(dir); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	path := filepath.Join(dir, "pkg", "main.go")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if string(content) != sourceCode.Content {
+		t.Errorf("Expected content %q, got %q", sourceCode.Content, content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat saved file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+	}
+
+	// Replacing an existing file leaves no temporary files behind
+	sourceCode.Content = "package other\n"
+	if err := sourceCode.Save(dir); err != nil {
+		t.Fatalf("Failed to save again: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only main.go, found %d entries", len(entries))
+	}
+	if content, _ := os.ReadFile(path); string(content) != "package other\n" {
+		t.Errorf("Expected the file to be replaced, got %q", content)
+	}
+}
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DefaultDirMode is the permission of directories created by a Writer without a DirMode.
+const DefaultDirMode os.FileMode = 0755
+
+// Writer saves source code files under Root, creating the root and any nested directories
+// implied by a filename as needed.
+type Writer struct {
+	// Root is the output directory.
+	Root string
+	// DirMode is the permission of created directories, DefaultDirMode if zero.
+	DirMode os.FileMode
+	// FileMode is the permission of files without their own Mode, DefaultFileMode if zero.
+	FileMode os.FileMode
+	// Output receives progress messages, os.Stderr if nil.
+	Output io.Writer
+	// Verbose also reports every directory that is created.
+	Verbose bool
+}
+
+// Write saves c to its Filename inside Root. The filename must stay inside Root, see ResolvePath.
+// If the file already holds exactly this content it is left untouched, keeping its modification time
+// stable for build tools and file watchers, and Write reports false. Otherwise the content is written
+// to a temporary file in the destination directory and renamed into place, so an interrupted run
+// never leaves a truncated file behind.
+// The file gets c.Mode if set; otherwise FileMode, made executable when the content starts with a shebang.
+func (w Writer) Write(c SourceCode) (bool, error) {
+	path, err := ResolvePath(w.Root, c.Filename)
+	if err != nil {
+		return false, err
+	}
+	mode := w.fileMode(c)
+	if unchanged, err := sameContent(path, c.Content); err != nil {
+		return false, err
+	} else if unchanged {
+		if w.Verbose {
+			fmt.Fprintf(w.output(), "Unchanged file: %s in %s\n", c.Filename, w.Root)
+		}
+		// The content is current but the permission may not be; chmod leaves the mtime alone
+		return false, os.Chmod(path, mode)
+	}
+
+	fmt.Fprintf(w.output(), "Saving file: %s in %s\n", c.Filename, w.Root)
+	if err := w.mkdirAll(filepath.Dir(path)); err != nil {
+		return false, err
+	}
+	return true, writeFileAtomic(path, []byte(c.Content), mode)
+}
+
+// sameContent reports whether path is a regular file holding exactly content. The size is
+// compared first so that most changed files are detected without reading them.
+func sameContent(path, content string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != int64(len(content)) {
+		return false, nil
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return string(existing) == content, nil
+}
+
+// fileMode returns the permission c is written with.
+func (w Writer) fileMode(c SourceCode) os.FileMode {
+	if c.Mode != 0 {
+		return c.Mode
+	}
+	mode := w.FileMode
+	if mode == 0 {
+		mode = DefaultFileMode
+	}
+	if HasShebang(c.Content) {
+		return Executable(mode)
+	}
+	return mode
+}
+
+// mkdirAll creates dir and any missing parents with DirMode, reporting each one when verbose.
+func (w Writer) mkdirAll(dir string) error {
+	var missing []string
+	for current := dir; ; current = filepath.Dir(current) {
+		info, err := os.Stat(current)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", current)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, current)
+		if parent := filepath.Dir(current); parent == current {
+			break
+		}
+	}
+
+	mode := w.DirMode
+	if mode == 0 {
+		mode = DefaultDirMode
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], mode); os.IsExist(err) {
+			// Created by someone else in the meantime, so its mode is not ours to set
+			continue
+		} else if err != nil {
+			return err
+		}
+		// Apply the exact mode rather than leaving it to the umask
+		if err := os.Chmod(missing[i], mode); err != nil {
+			return err
+		}
+		if w.Verbose {
+			fmt.Fprintf(w.output(), "Created directory: %s (%04o)\n", missing[i], mode)
+		}
+	}
+	return nil
+}
+
+func (w Writer) output() io.Writer {
+	if w.Output == nil {
+		return os.Stderr
+	}
+	return w.Output
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriterCreatesNestedDirectories(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "out", "missing")
+	var output bytes.Buffer
+	writer := Writer{Root: root, DirMode: 0750, Output: &output, Verbose: true}
+
+	if _, err := writer.Write(SourceCode{Filename: "a/b/main.go", Content: "package main\n"}); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "a", "b", "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read written file: %v", err)
+	}
+	if string(content) != "package main\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	for _, dir := range []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b")} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", dir, err)
+		}
+		if info.Mode().Perm() != 0750 {
+			t.Errorf("Expected %s to have mode 0750, got %04o", dir, info.Mode().Perm())
+		}
+		if !strings.Contains(output.String(), "Created directory: "+dir+" (0750)") {
+			t.Errorf("Expected verbose output to report %s, got:\n%s", dir, output.String())
+		}
+	}
+}
+
+func TestWriterDefaultDirMode(t *testing.T) {
+	// The umask must not weaken the configured mode
+	defer syscall.Umask(syscall.Umask(0077))
+
+	root := t.TempDir()
+	var output bytes.Buffer
+	writer := Writer{Root: root, Output: &output}
+
+	if _, err := writer.Write(SourceCode{Filename: "pkg/lib.go", Content: "package pkg\n"}); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(root, "pkg"))
+	if err != nil {
+		t.Fatalf("Failed to stat directory: %v", err)
+	}
+	if info.Mode().Perm() != DefaultDirMode {
+		t.Errorf("Expected mode %04o, got %04o", DefaultDirMode, info.Mode().Perm())
+	}
+	if strings.Contains(output.String(), "Created directory") {
+		t.Errorf("Expected no directory report without Verbose, got:\n%s", output.String())
+	}
+}
+
+func TestWriterRejectsFileInPlaceOfDirectory(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a"), []byte("file"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	writer := Writer{Root: root, Output: &bytes.Buffer{}}
+	if _, err := writer.Write(SourceCode{Filename: "a/b.go", Content: "package a\n"}); err == nil {
+		t.Error("Expected an error when a file is in the way of a directory")
+	}
+}
+
+func TestWriterFileModes(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(0077))
+
+	tests := []struct {
+		name     string
+		fileMode os.FileMode
+		source   SourceCode
+		expected os.FileMode
+	}{
+		{"default", 0, SourceCode{Content: "echo hi\n"}, 0644},
+		{"shebang", 0, SourceCode{Content: "#!/bin/sh\necho hi\n"}, 0755},
+		{"configured", 0600, SourceCode{Content: "echo hi\n"}, 0600},
+		{"configured shebang", 0640, SourceCode{Content: "#!/bin/sh\necho hi\n"}, 0750},
+		{"explicit mode wins", 0600, SourceCode{Content: "#!/bin/sh\necho hi\n", Mode: 0700}, 0700},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			tt.source.Filename = "script.sh"
+			writer := Writer{Root: root, FileMode: tt.fileMode, Output: &bytes.Buffer{}}
+			if _, err := writer.Write(tt.source); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			info, err := os.Stat(filepath.Join(root, "script.sh"))
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+			if info.Mode().Perm() != tt.expected {
+				t.Errorf("Expected mode %04o, got %04o", tt.expected, info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestWriterLeavesUnchangedFiles(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	writer := Writer{Root: root, Output: &bytes.Buffer{}}
+	source := SourceCode{Filename: "main.go", Content: "package main\n"}
+
+	if written, err := writer.Write(source); err != nil || !written {
+		t.Fatalf("Expected the first write to happen, got %v, %v", written, err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	if written, err := writer.Write(source); err != nil || written {
+		t.Fatalf("Expected identical content to be left alone, got %v, %v", written, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("Expected mtime %v to be kept, got %v", past, info.ModTime())
+	}
+
+	// Same size, different content
+	source.Content = "package mian\n"
+	if written, err := writer.Write(source); err != nil || !written {
+		t.Fatalf("Expected changed content to be written, got %v, %v", written, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != source.Content {
+		t.Errorf("Expected %q, got %q", source.Content, content)
+	}
+
+	// An unchanged file still gets the requested mode
+	source.Mode = 0600
+	if written, err := writer.Write(source); err != nil || written {
+		t.Fatalf("Expected identical content to be left alone, got %v, %v", written, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %04o", info.Mode().Perm())
+	}
+}